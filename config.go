@@ -1,17 +1,43 @@
 package main
 
 import (
+	"bupt-hotel/handlers"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	DatabasePath string
 	JWTSecret    string
 	ServerPort   string
+
+	AccessTokenTTL  time.Duration // 访问令牌有效期
+	RefreshTokenTTL time.Duration // 刷新令牌有效期
+
+	ShutdownTimeout time.Duration // 优雅关闭的最长等待时间
+
+	SchedulerPolicy string                   // 空调调度策略
+	ThermalModel    string                   // 房间热模型
+	Scheduler       handlers.SchedulerConfig // 空调调度器参数
+	Tariff          handlers.Tariff          // 空调计费规则
 }
 
-func LoadConfig() *Config {
+// fileConfig 配置文件结构（YAML），环境变量优先于配置文件
+type fileConfig struct {
+	Scheduler struct {
+		Policy                   string `yaml:"policy"`
+		ThermalModel             string `yaml:"thermal_model"`
+		handlers.SchedulerConfig `yaml:",inline"`
+	} `yaml:"scheduler"`
+	Tariff handlers.Tariff `yaml:"tariff"`
+}
+
+func LoadConfig() (*Config, error) {
 	// 从环境变量获取配置，如果没有则使用默认值
 	jwtSecret := os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
@@ -28,11 +54,106 @@ func LoadConfig() *Config {
 		serverPort = ":8099" // 默认端口
 	}
 
-	log.Printf("配置加载完成: 数据库路径=%s, 服务端口=%s", databasePath, serverPort)
+	accessTokenTTL := 15 * time.Minute // 默认15分钟
+	if err := envDuration("ACCESS_TOKEN_TTL", &accessTokenTTL); err != nil {
+		return nil, err
+	}
+	refreshTokenTTL := 7 * 24 * time.Hour // 默认7天
+	if err := envDuration("REFRESH_TOKEN_TTL", &refreshTokenTTL); err != nil {
+		return nil, err
+	}
+
+	shutdownTimeout := 15 * time.Second // 默认15秒，需大于长轮询的10秒等待
+	if err := envDuration("SHUTDOWN_TIMEOUT", &shutdownTimeout); err != nil {
+		return nil, err
+	}
+
+	// 读取配置文件（可选）
+	var file fileConfig
+	file.Scheduler.Policy = "priority_time_slice" // 默认优先级+时间片轮转
+	file.Scheduler.ThermalModel = "linear"        // 默认固定步长线性模型
+	file.Scheduler.SchedulerConfig = handlers.DefaultSchedulerConfig()
+	file.Tariff = handlers.DefaultTariff() // 配置文件中未设置的风速费率保留默认值
+	if configFile := os.Getenv("CONFIG_FILE"); configFile != "" {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取配置文件失败: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("解析配置文件失败: %w", err)
+		}
+	}
+
+	schedulerPolicy := file.Scheduler.Policy
+	if policy := os.Getenv("SCHEDULER_POLICY"); policy != "" {
+		schedulerPolicy = policy
+	}
+
+	thermalModel := file.Scheduler.ThermalModel
+	if model := os.Getenv("THERMAL_MODEL"); model != "" {
+		thermalModel = model
+	}
+
+	scheduler := file.Scheduler.SchedulerConfig
+	if err := envInt("SCHEDULER_MAX_SERVING", &scheduler.MaxServing); err != nil {
+		return nil, err
+	}
+	if err := envInt("SCHEDULER_SORT_INTERVAL", &scheduler.SortInterval); err != nil {
+		return nil, err
+	}
+	if err := envInt("SCHEDULER_TIME_SLICE", &scheduler.TimeSlice); err != nil {
+		return nil, err
+	}
+	if err := envInt("SCHEDULER_REWARM_DELTA", &scheduler.RewarmDelta); err != nil {
+		return nil, err
+	}
+	if err := envDuration("SCHEDULER_TICK_INTERVAL", &scheduler.TickInterval); err != nil {
+		return nil, err
+	}
+
+	log.Printf("配置加载完成: 数据库路径=%s, 服务端口=%s, 调度策略=%s, 房间热模型=%s", databasePath, serverPort, schedulerPolicy, thermalModel)
 
 	return &Config{
 		DatabasePath: databasePath,
 		JWTSecret:    jwtSecret,
 		ServerPort:   serverPort,
+
+		AccessTokenTTL:  accessTokenTTL,
+		RefreshTokenTTL: refreshTokenTTL,
+
+		ShutdownTimeout: shutdownTimeout,
+
+		SchedulerPolicy: schedulerPolicy,
+		ThermalModel:    thermalModel,
+		Scheduler:       scheduler,
+		Tariff:          file.Tariff,
+	}, nil
+}
+
+// envInt 从环境变量读取整数，未设置时保留原值
+func envInt(key string, target *int) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("环境变量 %s 不是有效的整数: %s", key, value)
+	}
+	*target = n
+	return nil
+}
+
+// envDuration 从环境变量读取时长（如 3s、500ms），未设置时保留原值
+func envDuration(key string, target *time.Duration) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("环境变量 %s 不是有效的时长: %s", key, value)
 	}
+	*target = d
+	return nil
 }
@@ -3,6 +3,7 @@ package database
 import (
 	"bupt-hotel/models"
 	"log"
+	"strings"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -10,10 +11,20 @@ import (
 
 var DB *gorm.DB
 
+// sqliteDSN 为数据库路径追加连接参数：
+// 写事务以 BEGIN IMMEDIATE 开始并在数据库被锁定时最多等待5秒，避免并发写入直接返回 database is locked
+func sqliteDSN(databasePath string) string {
+	separator := "?"
+	if strings.Contains(databasePath, "?") {
+		separator = "&"
+	}
+	return databasePath + separator + "_busy_timeout=5000&_txlock=immediate"
+}
+
 // InitDatabase 初始化数据库连接
 func InitDatabase(databasePath string) error {
 	var err error
-	DB, err = gorm.Open(sqlite.Open(databasePath), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open(sqliteDSN(databasePath)), &gorm.Config{})
 	if err != nil {
 		return err
 	}
@@ -27,6 +38,15 @@ func InitDatabase(databasePath string) error {
 		&models.AirConditionerDetail{},
 		&models.RoomOperation{},
 		&models.AirConditionerOperation{},
+		&models.Reservation{},
+		&models.FolioEntry{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.Role{},
+		&models.Device{},
+		&models.LoginThrottle{},
+		&models.LoginFailure{},
+		&models.AuditLog{},
 	)
 	if err != nil {
 		return err
@@ -39,6 +59,18 @@ func InitDatabase(databasePath string) error {
 	return nil
 }
 
+// CloseDatabase 关闭数据库连接
+func CloseDatabase() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
 // initializeData 初始化基础数据
 func initializeData() {
 	// 初始化房间类型数据
@@ -63,7 +95,7 @@ func initializeData() {
 			room := models.RoomInfo{
 				RoomID:     100 + i,
 				RoomTypeID: 1,
-				State:      0, // 空房
+				State:      models.RoomVacantClean,
 				DailyRate:  float32(TestRoomDailyRate[i-1]),
 				Deposit:    500.00,
 			}
@@ -83,7 +115,7 @@ func initializeData() {
 				room := models.RoomInfo{
 					RoomID:     j*100 + i,
 					RoomTypeID: roomTypeID,
-					State:      0, // 空房
+					State:      models.RoomVacantClean,
 					DailyRate:  float32(80.00 + j*100),
 					Deposit:    500.00,
 				}
@@ -102,15 +134,23 @@ func initializeData() {
 
 	}
 
+	// 初始化角色数据，已存在的角色保留管理员修改后的权限
+	for _, role := range models.GetDefaultRoles() {
+		role := role
+		if err := DB.Where("name = ?", role.Name).FirstOrCreate(&role).Error; err != nil {
+			log.Printf("初始化角色 %s 失败: %v", role.Name, err)
+		}
+	}
+
 	// 检查是否已有管理员账户
 	var adminCount int64
-	DB.Model(&models.User{}).Where("identity = ?", "administrator").Count(&adminCount)
+	DB.Model(&models.User{}).Where("identity = ?", models.RoleAdministrator).Count(&adminCount)
 	if adminCount == 0 {
 		// 创建默认管理员账户
 		admin := models.User{
 			Username: "admin",
 			Password: "$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi", // password
-			Identity: "administrator",
+			Identity: models.RoleAdministrator,
 		}
 		DB.Create(&admin)
 		log.Println("创建默认管理员账户: admin/password")
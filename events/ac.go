@@ -0,0 +1,48 @@
+package events
+
+import "time"
+
+// 空调调度事件名称
+const (
+	NameACAdmitted      = "ac_admitted"
+	NameACPreempted     = "ac_preempted"
+	NameACTargetReached = "ac_target_reached"
+	NameACShutdown      = "ac_shutdown"
+	NameACWarming       = "ac_warming"
+	NameACRequeued      = "ac_requeued"
+)
+
+// AC 空调调度事件的公共字段
+type AC struct {
+	Tick    int       `json:"tick"`     // 事件发生时调度器的tick计数
+	ACID    int       `json:"ac_id"`    // 空调ID
+	RoomID  int       `json:"room_id"`  // 房间号
+	BillID  int       `json:"bill_id"`  // 账单号
+	ACState int       `json:"ac_state"` // 事件发生后的空调状态：0-运行 1-在等待序列 2-关机回温 3-达到目标温度回温
+	Time    time.Time `json:"time"`     // 事件发生时调度器时钟的时间
+}
+
+// ACAdmitted 空调进入服务队列
+type ACAdmitted struct{ AC }
+
+// ACPreempted 空调被抢占，从服务队列退回等待序列（优先级抢占或时间片用完）
+type ACPreempted struct{ AC }
+
+// ACTargetReached 服务中的空调达到目标温度
+type ACTargetReached struct{ AC }
+
+// ACShutdown 空调被关机，之后进入关机回温
+type ACShutdown struct{ AC }
+
+// ACWarming 关机或达到目标温度的空调移入回温队列
+type ACWarming struct{ AC }
+
+// ACRequeued 回温队列中的空调回到缓冲队列（温差达到回温温差或重新开机）
+type ACRequeued struct{ AC }
+
+func (ACAdmitted) EventName() string      { return NameACAdmitted }
+func (ACPreempted) EventName() string     { return NameACPreempted }
+func (ACTargetReached) EventName() string { return NameACTargetReached }
+func (ACShutdown) EventName() string      { return NameACShutdown }
+func (ACWarming) EventName() string       { return NameACWarming }
+func (ACRequeued) EventName() string      { return NameACRequeued }
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"log"
+	"sync"
+)
+
+// Event 事件总线上传递的事件
+type Event interface {
+	// EventName 返回事件名称，如 ac_admitted
+	EventName() string
+}
+
+// DefaultQueueSize 每个订阅者默认缓存的未处理事件数
+const DefaultQueueSize = 256
+
+// Bus 进程内事件总线
+// 发布不会阻塞：每个订阅者有独立的缓存队列和处理协程，按发布顺序依次处理事件；
+// 订阅者处理过慢导致队列已满时丢弃新事件
+type Bus struct {
+	mu     sync.RWMutex
+	closed bool
+	subs   map[*subscription]struct{}
+	wg     sync.WaitGroup
+}
+
+type subscription struct {
+	name    string
+	handler func(Event)
+	queue   chan Event
+}
+
+// New 创建事件总线
+func New() *Bus {
+	return &Bus{subs: make(map[*subscription]struct{})}
+}
+
+// Subscribe 订阅所有事件，name用于日志。返回取消订阅函数，取消后已缓存的事件仍会处理完
+func (b *Bus) Subscribe(name string, handler func(Event)) func() {
+	sub := &subscription{
+		name:    name,
+		handler: handler,
+		queue:   make(chan Event, DefaultQueueSize),
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return func() {}
+	}
+	b.subs[sub] = struct{}{}
+	b.wg.Add(1)
+	b.mu.Unlock()
+
+	go func() {
+		defer b.wg.Done()
+		for event := range sub.queue {
+			sub.handler(event)
+		}
+	}()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subs[sub]; ok {
+			delete(b.subs, sub)
+			close(sub.queue)
+		}
+	}
+}
+
+// SubscribeTo 只订阅类型为T的事件
+func SubscribeTo[T Event](b *Bus, name string, handler func(T)) func() {
+	return b.Subscribe(name, func(event Event) {
+		if e, ok := event.(T); ok {
+			handler(e)
+		}
+	})
+}
+
+// Publish 发布事件，总线关闭后发布的事件被忽略
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		select {
+		case sub.queue <- event:
+		default:
+			log.Printf("事件订阅者 %s 处理过慢，丢弃事件 %s", sub.name, event.EventName())
+		}
+	}
+}
+
+// Close 关闭事件总线，等待所有订阅者处理完已缓存的事件
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub.queue)
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"bupt-hotel/database"
+	"bupt-hotel/middleware"
 	"bupt-hotel/models"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -53,16 +55,15 @@ func ControlAirConditioner(c *gin.Context) {
 	}
 
 	// 从房间操作表中获取当前房间的有效订单号
-	var roomOperation models.RoomOperation
-	if err := database.DB.Where("room_id = ? AND operation_type = ?", roomID, "checkin").Order("operation_time DESC").First(&roomOperation).Error; err != nil {
+	roomIDInt, _ := strconv.Atoi(roomID)
+	billID, err := currentBillID(database.DB, roomIDInt)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "该房间没有有效的入住记录，无法操作空调",
 		})
 		return
 	}
 
-	// 使用从房间操作表获取的订单号
-	billID := roomOperation.BillID
 	if billID == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "房间操作记录中订单号无效",
@@ -87,6 +88,10 @@ func ControlAirConditioner(c *gin.Context) {
 		OperationState: req.OperationType,
 	}
 
+	// 上一次操作的设置，记录到审计日志
+	var previous models.AirConditionerOperation
+	database.DB.Where("room_id = ? AND bill_id = ?", ac.RoomID, billID).Order("created_at DESC").Limit(1).Find(&previous)
+
 	// 根据操作类型设置参数
 	switch req.OperationType {
 	case 0: // 开机
@@ -143,68 +148,18 @@ func ControlAirConditioner(c *gin.Context) {
 	operation.EnvironmentTemp = ac.EnvironmentTemp
 	operation.CurrentTemp = ac.EnvironmentTemp // 初始当前温度等于环境温度
 
-	// 保存操作记录
-	if err := database.DB.Create(&operation).Error; err != nil {
+	// 保存操作记录并向调度器发送指令
+	if err := applyACOperation(ac, billID, &operation); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "保存操作记录失败",
 		})
 		return
 	}
-
-	// 向调度器发送指令
-	scheduler := GetScheduler()
-	switch req.OperationType {
-	case 0: // 开机
-		// 根据风速设置优先级
-		var priority int
-		switch operation.Speed {
-		case "high":
-			priority = 1
-		case "medium":
-			priority = 2
-		case "low":
-			priority = 3
-		default:
-			priority = 2 // 默认中等优先级
-		}
-
-		schedulerObj := &models.Scheduler{
-			ACID:               ac.ID,
-			RoomID:             ac.RoomID,
-			BillID:             billID,
-			ACState:            0, // 运行状态
-			Mode:               operation.Mode,
-			Priority:           priority,
-			CurrentSpeed:       operation.Speed,
-			CurrentTemp:        ac.EnvironmentTemp,
-			TargetTemp:         operation.TargetTemp,
-			EnvironmentTemp:    ac.EnvironmentTemp,
-			CurrentCost:        0,
-			TotalCost:          0,
-			CurrentRunningTime: 0,
-			RunningTime:        0,
-			RoundRobinCount:    0,
-		}
-		scheduler.AddRequest(schedulerObj)
-	case 1: // 关机
-		scheduler.RemoveRequest(ac.ID)
-	case 2: // 调温或其他设置
-		// 根据风速设置优先级
-		var priority int
-		switch operation.Speed {
-		case "high":
-			priority = 1
-		case "medium":
-			priority = 2
-		case "low":
-			priority = 3
-		default:
-			priority = 2 // 默认中等优先级
-		}
-
-		// 调温操作：查找缓冲队列中的对应空调并更新目标温度和风速
-		scheduler.UpdateACInBuffer(ac.ID, operation.TargetTemp, operation.Speed, priority)
+	var before interface{}
+	if previous.ID != 0 {
+		before = acAuditSummary(previous)
 	}
+	middleware.SetAuditChange(c, before, acAuditSummary(operation))
 
 	// 直接返回基于操作记录的响应
 	responseData := &ACStatusResponse{
@@ -232,16 +187,15 @@ func GetACStatusLongPolling(c *gin.Context) {
 		return
 	}
 	// 从房间操作表中获取当前房间的有效订单号
-	var roomOperation models.RoomOperation
-	if err := database.DB.Where("room_id = ? AND operation_type = ?", roomID, "checkin").Order("operation_time DESC").First(&roomOperation).Error; err != nil {
+	roomIDInt, _ := strconv.Atoi(roomID)
+	billID, err := currentBillID(database.DB, roomIDInt)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "该房间没有有效的入住记录，无法获取空调状态",
 		})
 		return
 	}
 
-	// 使用从房间操作表获取的订单号
-	billID := roomOperation.BillID
 	if billID == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "房间操作记录中订单号无效",
@@ -292,6 +246,84 @@ func GetACStatusLongPolling(c *gin.Context) {
 	}
 }
 
+// applyACOperation 保存空调操作记录并向调度器发送对应的指令
+func applyACOperation(ac models.AirConditioner, billID int, operation *models.AirConditionerOperation) error {
+	if err := database.DB.Create(operation).Error; err != nil {
+		return err
+	}
+
+	// 向调度器发送指令
+	scheduler := GetScheduler()
+	switch operation.OperationState {
+	case 0: // 开机
+		// 根据风速设置优先级
+		priority := PriorityForSpeed(operation.Speed)
+
+		// 获取房间类型，供房间热模型使用
+		var room models.RoomInfo
+		database.DB.Select("room_type_id").Where("room_id = ?", ac.RoomID).First(&room)
+
+		schedulerObj := &models.Scheduler{
+			ACID:               ac.ID,
+			RoomID:             ac.RoomID,
+			RoomTypeID:         room.RoomTypeID,
+			BillID:             billID,
+			ACState:            0, // 运行状态
+			Mode:               operation.Mode,
+			Priority:           priority,
+			CurrentSpeed:       operation.Speed,
+			CurrentTemp:        ac.EnvironmentTemp,
+			TargetTemp:         operation.TargetTemp,
+			EnvironmentTemp:    ac.EnvironmentTemp,
+			CurrentCost:        0,
+			TotalCost:          0,
+			CurrentRunningTime: 0,
+			RunningTime:        0,
+			RoundRobinCount:    0,
+		}
+		scheduler.AddRequest(schedulerObj)
+	case 1: // 关机
+		scheduler.RemoveRequest(ac.ID)
+	case 2: // 调温或其他设置
+		// 根据风速设置优先级
+		priority := PriorityForSpeed(operation.Speed)
+
+		// 调温操作：查找缓冲队列中的对应空调并更新目标温度和风速
+		scheduler.UpdateACInBuffer(ac.ID, operation.TargetTemp, operation.Speed, priority)
+	}
+	return nil
+}
+
+// shutdownAirConditioner 关闭房间当前账单下开着的空调，返回关机前的设置以及空调是否开着
+// 关机与客人关机一样保存关机操作记录并通知调度器，空调已经关机时不做任何操作
+func shutdownAirConditioner(roomID, billID int) (models.AirConditionerOperation, bool, error) {
+	lastOp, on := activeACSettings(roomID, billID)
+	if !on {
+		return lastOp, false, nil
+	}
+
+	var ac models.AirConditioner
+	if err := database.DB.Where("room_id = ?", roomID).First(&ac).Error; err != nil {
+		return lastOp, true, fmt.Errorf("未找到房间 %d 的空调: %w", roomID, err)
+	}
+	shutdown := models.AirConditionerOperation{
+		BillID:          billID,
+		RoomID:          roomID,
+		AcID:            ac.ID,
+		OperationState:  1,
+		Speed:           lastOp.Speed,
+		Mode:            lastOp.Mode,
+		TargetTemp:      lastOp.TargetTemp,
+		EnvironmentTemp: ac.EnvironmentTemp,
+		CurrentTemp:     ac.EnvironmentTemp,
+		SwitchCount:     lastOp.SwitchCount + 1,
+	}
+	if err := applyACOperation(ac, billID, &shutdown); err != nil {
+		return lastOp, true, fmt.Errorf("关闭房间 %d 的空调失败: %w", roomID, err)
+	}
+	return lastOp, true, nil
+}
+
 // getACCurrentStatus 获取空调当前状态
 func getACCurrentStatus(roomID string, billID int) *ACStatusResponse {
 	// 获取该房间和订单的最新空调状态记录
@@ -323,6 +355,36 @@ func getACCurrentStatus(roomID string, billID int) *ACStatusResponse {
 	return convertToStatusResponse(detail)
 }
 
+// PriorityForSpeed 根据风速获取调度优先级：high-1 medium-2 low-3
+func PriorityForSpeed(speed string) int {
+	switch speed {
+	case "high":
+		return 1
+	case "medium":
+		return 2
+	case "low":
+		return 3
+	default:
+		return 2 // 默认中等优先级
+	}
+}
+
+// ACStatusText 将空调状态转换为中文描述
+func ACStatusText(status int) string {
+	switch status {
+	case 0:
+		return "运行"
+	case 1:
+		return "在等待序列"
+	case 2:
+		return "关机回温"
+	case 3:
+		return "达到目标温度回温"
+	default:
+		return "未知"
+	}
+}
+
 // getACStatusFromOperation 根据操作类型获取空调状态
 func getACStatusFromOperation(operationType int) int {
 	switch operationType {
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"bupt-hotel/database"
+)
+
+const (
+	wsWriteTimeout = 10 * time.Second // 单条消息写超时
+	wsPongTimeout  = 60 * time.Second // 超过该时间未收到pong视为连接断开
+	wsPingInterval = 30 * time.Second // ping间隔，需小于wsPongTimeout
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// 与全局CORS配置保持一致，允许任意来源的房间面板连接
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// ACStatusWebSocket 通过WebSocket实时推送空调状态
+// 连接建立后先发送一次当前状态，之后每当调度器tick结束且状态发生变化时推送最新状态
+func ACStatusWebSocket(c *gin.Context) {
+	roomID, err := strconv.Atoi(c.Param("room_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "房间ID无效",
+		})
+		return
+	}
+
+	// 从房间操作表中获取当前房间的有效订单号
+	billID, err := currentBillID(database.DB, roomID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "该房间没有有效的入住记录，无法获取空调状态",
+		})
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade失败时已向客户端返回错误响应
+		log.Printf("房间 %d 空调状态WebSocket升级失败: %v", roomID, err)
+		return
+	}
+	defer conn.Close()
+
+	hub := GetStatusHub()
+	key := StatusKey{RoomID: roomID, BillID: billID}
+	updates, cancel := hub.Subscribe(key)
+	defer cancel()
+
+	log.Printf("房间 %d 空调状态WebSocket已连接（账单 %d）", roomID, billID)
+
+	// 读协程：处理pong和客户端关闭，客户端发送的其他消息忽略
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// 发送当前状态：优先使用调度器最近推送的状态，没有时从数据库读取
+	initial := hub.Latest(key)
+	if initial == nil {
+		initial = getACCurrentStatus(strconv.Itoa(roomID), billID)
+	}
+	if initial != nil {
+		if err := writeACStatus(conn, initial); err != nil {
+			return
+		}
+	}
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case status, ok := <-updates:
+			if !ok {
+				// 推送中心已关闭，服务正在退出
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "服务关闭"),
+					time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if err := writeACStatus(conn, status); err != nil {
+				log.Printf("房间 %d 空调状态推送失败: %v", roomID, err)
+				return
+			}
+
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+
+		case <-done:
+			log.Printf("房间 %d 空调状态WebSocket已断开", roomID)
+			return
+		}
+	}
+}
+
+// writeACStatus 向WebSocket连接写入空调状态，消息格式与长轮询接口的响应一致
+func writeACStatus(conn *websocket.Conn, status *ACStatusResponse) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(gin.H{
+		"message": "获取空调状态成功",
+		"data":    status,
+	})
+}
@@ -2,22 +2,24 @@ package handlers
 
 import (
 	"bupt-hotel/models"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetAdminSchedulerStatus 获取调度器状态（管理员专用接口）
-// 返回运行队列、缓存队列第4个空调后的部分、回温队列的详细信息
+// GetAdminSchedulerStatus 获取调度器状态（需要 scheduler:read 权限）
+// 返回运行队列、缓存队列中等待的部分、回温队列的详细信息以及只读的调度器参数和计费规则
 func GetAdminSchedulerStatus(c *gin.Context) {
 	scheduler := GetScheduler()
 	scheduler.mu.RLock()
 	defer scheduler.mu.RUnlock()
 
-	// 获取缓存队列第4个空调后的部分
+	// 获取缓存队列中未进入服务队列的部分（默认即第4个空调之后）
 	var bufferQueueAfterFourth []*models.Scheduler
-	if len(scheduler.bufferQueue) > 3 {
-		bufferQueueAfterFourth = scheduler.bufferQueue[3:]
+	if len(scheduler.bufferQueue) > scheduler.config.MaxServing {
+		bufferQueueAfterFourth = scheduler.bufferQueue[scheduler.config.MaxServing:]
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -27,8 +29,17 @@ func GetAdminSchedulerStatus(c *gin.Context) {
 				"is_running":       scheduler.isRunning,
 				"tick_count":       scheduler.tickCount,
 				"current_priority": scheduler.currentPriority,
+				"policy":           scheduler.policy.Name(),
 				"total_requests":   len(scheduler.schedulers),
 			},
+			"config": gin.H{
+				"max_serving":   scheduler.config.MaxServing,
+				"sort_interval": scheduler.config.SortInterval,
+				"time_slice":    scheduler.config.TimeSlice,
+				"rewarm_delta":  scheduler.config.RewarmDelta,
+				"tick_interval": scheduler.config.TickInterval.String(),
+			},
+			"tariff": scheduler.tariff,
 			"queues": gin.H{
 				"serving_queue": gin.H{
 					"count": len(scheduler.servingQueue),
@@ -48,3 +59,47 @@ func GetAdminSchedulerStatus(c *gin.Context) {
 	})
 }
 
+// sseKeepAliveInterval SSE心跳间隔，防止代理因长时间无数据断开连接
+const sseKeepAliveInterval = 15 * time.Second
+
+// StreamAdminScheduler 通过SSE推送调度器状态（需要 scheduler:read 权限）
+// 连接建立后先推送一次当前快照，之后每个tick推送 snapshot 事件，
+// 并将本tick内事件总线上的调度事件（进入服务、被抢占、达到目标温度、关机、移入回温、回到缓冲队列）作为独立事件推送
+func StreamAdminScheduler(c *gin.Context) {
+	feed := GetSchedulerFeed()
+	ticks, cancel := feed.Subscribe()
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("snapshot", GetScheduler().Snapshot())
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case tick, ok := <-ticks:
+			if !ok {
+				// 推送中心已关闭，服务正在退出
+				return false
+			}
+			for _, event := range tick.Events {
+				c.SSEvent(event.EventName(), event)
+			}
+			c.SSEvent("snapshot", tick.Snapshot)
+			return true
+
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			return true
+
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+// GetAuditLogs 查询审计日志（需要 audit:read 权限），按时间倒序
+// 可按actor_id、actor（用户名）、entity_type、entity_id、method和时间范围from/to筛选
+func GetAuditLogs(c *gin.Context) {
+	query := database.DB.Order("id DESC")
+	if actorID := c.Query("actor_id"); actorID != "" {
+		value, err := strconv.Atoi(actorID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的用户ID",
+			})
+			return
+		}
+		query = query.Where("actor_id = ?", value)
+	}
+	if actor := c.Query("actor"); actor != "" {
+		query = query.Where("actor_name = ?", actor)
+	}
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if method := c.Query("method"); method != "" {
+		query = query.Where("method = ?", method)
+	}
+	query, ok := filterTimeRange(c, query, "created_at")
+	if !ok {
+		return
+	}
+	limit, ok := queryLimit(c)
+	if !ok {
+		return
+	}
+
+	var logs []models.AuditLog
+	if err := query.Limit(limit).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取审计日志失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取审计日志成功",
+		"logs":    logs,
+	})
+}
+
+// roomAuditSummary 审计日志中房间状态的摘要
+func roomAuditSummary(room models.RoomInfo) gin.H {
+	return gin.H{
+		"room_id":       room.RoomID,
+		"state":         room.State,
+		"state_reason":  room.StateReason,
+		"client_id":     room.ClientID,
+		"client_name":   room.ClientName,
+		"checkout_time": room.CheckoutTime,
+	}
+}
+
+// acAuditSummary 审计日志中空调设置的摘要
+func acAuditSummary(operation models.AirConditionerOperation) gin.H {
+	return gin.H{
+		"bill_id":        operation.BillID,
+		"ac_id":          operation.AcID,
+		"operation_type": operation.OperationState,
+		"mode":           operation.Mode,
+		"speed":          operation.Speed,
+		"target_temp":    operation.TargetTemp,
+	}
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// reportsDir 退房时保存空调使用详单的目录
+const reportsDir = "./reports"
+
+// BillData 生成账单和空调使用详单所需的数据
+type BillData struct {
+	Checkin      models.RoomOperation  // 入住记录
+	Checkout     *models.RoomOperation // 退房记录，未退房时为nil
+	Folio        *Folio
+	ACOperations []models.AirConditionerOperation
+	ACDetails    []models.AirConditionerDetail
+}
+
+// folioEntryTypeNames 账单明细类型的中文名称
+var folioEntryTypeNames = map[string]string{
+	models.FolioRoomCharge: "房费",
+	models.FolioACCharge:   "空调费",
+	models.FolioDeposit:    "押金",
+	models.FolioAdjustment: "调账",
+	models.FolioSettlement: "结账",
+}
+
+// DownloadInvoice 下载账单，format 为 xlsx/csv/html，默认 xlsx
+func DownloadInvoice(c *gin.Context) {
+	downloadBillReport(c, "账单", buildInvoiceReport)
+}
+
+// DownloadACReport 下载空调使用详单，format 为 xlsx/csv/html，默认 xlsx
+func DownloadACReport(c *gin.Context) {
+	downloadBillReport(c, "空调使用详单", buildACReport)
+}
+
+// downloadBillReport 按需生成账单号对应的报表并作为附件返回
+func downloadBillReport(c *gin.Context, name string, build func(*BillData) *Report) {
+	format := c.DefaultQuery("format", ReportFormatXLSX)
+	contentType, ok := reportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "不支持的格式，可选 xlsx/csv/html",
+		})
+		return
+	}
+
+	checkin, ok := loadOwnBill(c)
+	if !ok {
+		return
+	}
+
+	data, err := LoadBillData(checkin.BillID)
+	if err != nil {
+		log.Printf("加载账单 %d 的报表数据失败: %v", checkin.BillID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "生成" + name + "失败",
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, build(data), format); err != nil {
+		log.Printf("生成账单 %d 的%s失败: %v", checkin.BillID, name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "生成" + name + "失败",
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("%s_%d_%d.%s", name, checkin.BillID, checkin.RoomID, format)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// loadOwnBill 加载URL中账单号对应的入住记录，没有报表权限时只能访问自己的账单；失败时已写入响应
+func loadOwnBill(c *gin.Context) (models.RoomOperation, bool) {
+	billID, err := strconv.Atoi(c.Param("bill_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的账单号",
+		})
+		return models.RoomOperation{}, false
+	}
+
+	checkin, err := loadCheckinOperation(database.DB, billID)
+	if err == nil {
+		userID, _ := c.Get("user_id")
+		if checkin.ClientID == strconv.Itoa(userID.(int)) || middleware.HasPermission(c, models.PermReportsRead) {
+			return checkin, true
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": "账单不存在",
+	})
+	return models.RoomOperation{}, false
+}
+
+// LoadBillData 加载账单号对应的入住记录、账单明细和空调使用记录
+func LoadBillData(billID int) (*BillData, error) {
+	folio, err := LoadFolio(database.DB, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	checkin, err := loadCheckinOperation(database.DB, billID)
+	if err != nil {
+		return nil, err
+	}
+	data := &BillData{Checkin: checkin, Folio: folio}
+
+	var checkouts []models.RoomOperation
+	if err := database.DB.Where("bill_id = ? AND operation_type = ?", billID, "checkout").Limit(1).Find(&checkouts).Error; err != nil {
+		return nil, err
+	}
+	if len(checkouts) > 0 {
+		data.Checkout = &checkouts[0]
+	}
+
+	if err := database.DB.Where("bill_id = ?", billID).Order("created_at ASC").Find(&data.ACOperations).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Where("bill_id = ?", billID).Order("created_at ASC").Find(&data.ACDetails).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ACCost 账单的空调费用：已入账的空调费，未结账时为截至当前的空调费
+func (d *BillData) ACCost() float64 {
+	var cost float64
+	for _, entries := range [][]models.FolioEntry{d.Folio.Entries, d.Folio.Pending} {
+		for _, entry := range entries {
+			if entry.EntryType == models.FolioACCharge {
+				cost += entry.Amount
+			}
+		}
+	}
+	return roundMoney(cost)
+}
+
+// buildACReport 生成空调使用详单
+func buildACReport(data *BillData) *Report {
+	operations := ReportSection{
+		Title:  "空调操作记录",
+		Header: []string{"序号", "操作时间", "操作类型", "目标温度", "风速", "模式"},
+	}
+	for i, op := range data.ACOperations {
+		// 将操作状态数字转换为中文描述
+		var operationDesc string
+		switch op.OperationState {
+		case 0:
+			operationDesc = "开机"
+		case 1:
+			operationDesc = "关机"
+		case 2:
+			operationDesc = "调温"
+		default:
+			operationDesc = "未知"
+		}
+
+		// 温度除以10显示实际温度
+		operations.Rows = append(operations.Rows, []interface{}{
+			i + 1, op.CreatedAt.Format("2006-01-02 15:04:05"), operationDesc,
+			float32(op.TargetTemp) / 10.0, op.Speed, op.Mode,
+		})
+	}
+
+	details := ReportSection{
+		Title:  "空调状态详细记录",
+		Header: []string{"序号", "记录时间", "当前温度", "目标温度", "风速", "模式", "当前费用", "总费用", "运行时间", "空调状态"},
+	}
+	for i, detail := range data.ACDetails {
+		details.Rows = append(details.Rows, []interface{}{
+			i + 1, detail.CreatedAt.Format("2006-01-02 15:04:05"),
+			float32(detail.CurrentTemp) / 10.0, float32(detail.TargetTemp) / 10.0,
+			detail.Speed, detail.Mode, detail.CurrentCost, detail.TotalCost,
+			detail.RunningTime, ACStatusText(detail.ACStatus),
+		})
+	}
+
+	return &Report{
+		Title: "空调使用报告",
+		Sheet: "空调使用详单",
+		Meta: []ReportField{
+			{Label: "账单号", Value: data.Checkin.BillID},
+			{Label: "房间号", Value: data.Checkin.RoomID},
+			{Label: "生成时间", Value: time.Now().Format("2006-01-02 15:04:05")},
+		},
+		Sections: []ReportSection{operations, details},
+		Summary: []ReportField{
+			{Label: "总操作次数", Value: len(data.ACOperations)},
+			{Label: "详细记录条数", Value: len(data.ACDetails)},
+			{Label: "空调总费用", Value: fmt.Sprintf("%.2f 元", data.ACCost())},
+		},
+	}
+}
+
+// buildInvoiceReport 生成账单：入住信息、账单明细、未入账费用和余额
+func buildInvoiceReport(data *BillData) *Report {
+	folio := data.Folio
+
+	checkoutTime := "未退房"
+	if data.Checkout != nil {
+		checkoutTime = data.Checkout.CheckoutTime.Format("2006-01-02 15:04:05")
+	}
+
+	entries := ReportSection{
+		Title:  "账单明细",
+		Header: []string{"序号", "入账时间", "类型", "说明", "数量", "单价", "金额"},
+	}
+	for i, entry := range folio.Entries {
+		entries.Rows = append(entries.Rows, invoiceRow(i+1, entry.CreatedAt.Format("2006-01-02 15:04:05"), entry))
+	}
+	sections := []ReportSection{entries}
+
+	if len(folio.Pending) > 0 {
+		pending := ReportSection{
+			Title:  "未入账费用（截至生成时间）",
+			Header: entries.Header,
+		}
+		for i, entry := range folio.Pending {
+			pending.Rows = append(pending.Rows, invoiceRow(i+1, "", entry))
+		}
+		sections = append(sections, pending)
+	}
+
+	state := "未结账"
+	if folio.Settled {
+		state = "已结账"
+	}
+	summary := []ReportField{
+		{Label: "应收合计", Value: fmt.Sprintf("%.2f 元", folio.Charges)},
+		{Label: "收款和减免合计", Value: fmt.Sprintf("%.2f 元", folio.Credits)},
+		{Label: "余额", Value: fmt.Sprintf("%.2f 元", folio.Balance)},
+		{Label: "状态", Value: state},
+	}
+	if !folio.Settled {
+		summary = append(summary, ReportField{Label: "预计余额", Value: fmt.Sprintf("%.2f 元", folio.Projected)})
+	}
+
+	return &Report{
+		Title: "酒店账单",
+		Sheet: "账单",
+		Meta: []ReportField{
+			{Label: "账单号", Value: data.Checkin.BillID},
+			{Label: "房间号", Value: data.Checkin.RoomID},
+			{Label: "客户姓名", Value: data.Checkin.ClientName},
+			{Label: "入住时间", Value: data.Checkin.CheckinTime.Format("2006-01-02 15:04:05")},
+			{Label: "退房时间", Value: checkoutTime},
+			{Label: "每日房费", Value: fmt.Sprintf("%.2f 元", data.Checkin.DailyRate)},
+			{Label: "生成时间", Value: time.Now().Format("2006-01-02 15:04:05")},
+		},
+		Sections: sections,
+		Summary:  summary,
+	}
+}
+
+// invoiceRow 账单明细表中的一行
+func invoiceRow(index int, postedAt string, entry models.FolioEntry) []interface{} {
+	typeName, ok := folioEntryTypeNames[entry.EntryType]
+	if !ok {
+		typeName = entry.EntryType
+	}
+	return []interface{}{index, postedAt, typeName, entry.Description, entry.Quantity, entry.UnitPrice, entry.Amount}
+}
+
+// saveACReportFile 将空调使用详单保存到reports目录，返回文件路径
+func saveACReportFile(data *BillData) (string, error) {
+	if err := os.MkdirAll(reportsDir, 0755); err != nil {
+		return "", err
+	}
+
+	filename := fmt.Sprintf("空调使用详单_%d_%d.xlsx", data.Checkin.BillID, data.Checkin.RoomID)
+	filePath := fmt.Sprintf("%s/%s", reportsDir, filename)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := WriteReport(file, buildACReport(data), ReportFormatXLSX); err != nil {
+		return "", err
+	}
+	return filePath, file.Close()
+}
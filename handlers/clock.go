@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 调度器使用的时钟接口，便于在测试和仿真中替换为虚拟时钟
+type Clock interface {
+	// Now 返回当前时间
+	Now() time.Time
+	// NewTicker 创建一个按间隔d触发的定时器
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker 定时器接口
+type Ticker interface {
+	// C 返回定时触发的通道
+	C() <-chan time.Time
+	// Stop 停止定时器
+	Stop()
+}
+
+// realClock 基于系统时间的时钟
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+// realTicker 包装time.Ticker
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time { return t.ticker.C }
+
+func (t *realTicker) Stop() { t.ticker.Stop() }
+
+// VirtualClock 虚拟时钟，时间只在调用Advance时前进
+// 使用虚拟时钟的调度器不会启动后台定时器，需要通过ACScheduler.Step手动推进tick
+type VirtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewVirtualClock 创建从start开始的虚拟时钟
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+// Now 返回虚拟时钟的当前时间
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance 将虚拟时钟向前推进d
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// NewTicker 返回一个永不自动触发的定时器
+func (c *VirtualClock) NewTicker(d time.Duration) Ticker {
+	return &virtualTicker{ch: make(chan time.Time)}
+}
+
+// virtualTicker 虚拟定时器，其通道不会被写入
+type virtualTicker struct {
+	ch chan time.Time
+}
+
+func (t *virtualTicker) C() <-chan time.Time { return t.ch }
+
+func (t *virtualTicker) Stop() {}
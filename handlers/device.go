@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// deviceKeyPrefix 设备密钥前缀，便于在日志和配置中辨认
+const deviceKeyPrefix = "dk_"
+
+// RegisterDeviceRequest 登记设备请求结构
+type RegisterDeviceRequest struct {
+	Name string `json:"name" binding:"required"`
+	AcID int    `json:"ac_id" binding:"required"` // 绑定的空调ID
+}
+
+// GetDevices 获取设备列表（需要 devices:manage 权限），可按ac_id和revoked筛选
+func GetDevices(c *gin.Context) {
+	query := database.DB.Order("id ASC")
+	if acID := c.Query("ac_id"); acID != "" {
+		value, err := strconv.Atoi(acID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的空调ID",
+			})
+			return
+		}
+		query = query.Where("ac_id = ?", value)
+	}
+	if revoked := c.Query("revoked"); revoked != "" {
+		value, err := strconv.ParseBool(revoked)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "revoked 必须是 true 或 false",
+			})
+			return
+		}
+		query = query.Where("revoked = ?", value)
+	}
+
+	var devices []models.Device
+	if err := query.Find(&devices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取设备列表失败",
+		})
+		return
+	}
+
+	list := make([]gin.H, 0, len(devices))
+	for _, device := range devices {
+		list = append(list, deviceInfo(device))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取设备列表成功",
+		"devices": list,
+	})
+}
+
+// RegisterDevice 登记设备并签发设备密钥（需要 devices:manage 权限）
+// 设备密钥只在响应中返回这一次，数据库中只保存哈希
+func RegisterDevice(c *gin.Context) {
+	var req RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var ac models.AirConditioner
+	if err := database.DB.First(&ac, req.AcID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "空调不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "登记设备失败",
+		})
+		return
+	}
+
+	secret, err := randomToken(24)
+	if err != nil {
+		log.Printf("生成设备密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "登记设备失败",
+		})
+		return
+	}
+	key := deviceKeyPrefix + secret
+
+	device := models.Device{
+		Name:      req.Name,
+		AcID:      ac.ID,
+		KeyHash:   middleware.HashDeviceKey(key),
+		KeyPrefix: key[:len(deviceKeyPrefix)+6],
+		CreatedBy: c.GetInt("user_id"),
+	}
+	if err := database.DB.Create(&device).Error; err != nil {
+		log.Printf("登记设备 %s 失败: %v", req.Name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "登记设备失败",
+		})
+		return
+	}
+
+	info := deviceInfo(device)
+	info["room_id"] = ac.RoomID
+	middleware.SetAuditEntity(c, models.AuditEntityDevice, device.ID)
+	middleware.SetAuditChange(c, nil, info)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "设备登记成功，请妥善保存设备密钥，密钥不会再次显示",
+		"device":  info,
+		"key":     key,
+	})
+}
+
+// RevokeDevice 吊销设备密钥（需要 devices:manage 权限），立即生效
+func RevokeDevice(c *gin.Context) {
+	deviceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的设备ID",
+		})
+		return
+	}
+
+	var device models.Device
+	var before gin.H
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&device, deviceID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		result := tx.Model(&models.Device{}).
+			Where("id = ? AND revoked = ?", deviceID, false).
+			Updates(map[string]interface{}{
+				"revoked":    true,
+				"revoked_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return conflictf("设备 %d 的密钥已吊销", deviceID)
+		}
+		before = deviceInfo(device)
+		device.Revoked = true
+		device.RevokedAt = &now
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "设备不存在",
+			})
+			return
+		}
+		respondBookingError(c, err, "吊销设备密钥失败")
+		return
+	}
+	middleware.SetAuditChange(c, before, deviceInfo(device))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "吊销设备密钥成功",
+		"device":  deviceInfo(device),
+	})
+}
+
+// GetCurrentDevice 获取当前设备的信息和绑定的房间（设备密钥认证），面板启动时用于确认绑定关系
+func GetCurrentDevice(c *gin.Context) {
+	var device models.Device
+	if err := database.DB.First(&device, c.GetInt("device_id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取设备信息失败",
+		})
+		return
+	}
+
+	info := deviceInfo(device)
+	info["room_id"] = c.GetInt("panel_room_id")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取设备信息成功",
+		"device":  info,
+	})
+}
+
+// deviceInfo 返回设备的公开信息，不包含密钥哈希
+func deviceInfo(device models.Device) gin.H {
+	return gin.H{
+		"id":           device.ID,
+		"name":         device.Name,
+		"ac_id":        device.AcID,
+		"key_prefix":   device.KeyPrefix,
+		"revoked":      device.Revoked,
+		"revoked_at":   device.RevokedAt,
+		"last_seen_at": device.LastSeenAt,
+		"last_seen_ip": device.LastSeenIP,
+		"created_by":   device.CreatedBy,
+		"created_at":   device.CreatedAt,
+	}
+}
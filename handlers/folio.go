@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// folioSystemOperator 系统自动记账的操作人
+const folioSystemOperator = "system"
+
+// Folio 账单：一次入住的全部明细和余额
+type Folio struct {
+	BillID     int                 `json:"bill_id"`
+	RoomID     int                 `json:"room_id"`
+	ClientID   string              `json:"client_id"`
+	ClientName string              `json:"client_name"`
+	Entries    []models.FolioEntry `json:"entries"`
+	Pending    []models.FolioEntry `json:"pending,omitempty"` // 未结账时截至当前的房费和空调费，退房时入账
+	Charges    float64             `json:"charges"`           // 已入账的应收费用合计
+	Credits    float64             `json:"credits"`           // 已入账的收款和减免合计
+	Balance    float64             `json:"balance"`           // 余额：正数为客人应付，负数为应退还客人
+	Projected  float64             `json:"projected_balance"` // 计入未入账费用后的余额
+	Settled    bool                `json:"settled"`
+}
+
+// FolioAdjustmentRequest 调账请求结构
+type FolioAdjustmentRequest struct {
+	Amount      float64 `json:"amount" binding:"required"` // 正数为加收，负数为减免
+	Description string  `json:"description" binding:"required"`
+}
+
+// StayCharges 截至某一时刻的入住费用
+type StayCharges struct {
+	Days     int     // 入住天数，不足一天按一天计
+	Nights   int     // 未入账的房费晚数：入住天数减去已入账的晚数（如换房时已入账原房间的房费）
+	RoomCost float64 // 未入账的房费，按当前房间的每日房费计算
+	ACCost   float64 // 空调费，已按计费规则计入最低消费
+}
+
+// GetFolio 获取账单明细，客户只能查看自己的账单
+func GetFolio(c *gin.Context) {
+	checkin, ok := loadOwnBill(c)
+	if !ok {
+		return
+	}
+
+	folio, err := LoadFolio(database.DB, checkin.BillID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取账单失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取账单成功",
+		"data":    folio,
+	})
+}
+
+// AddFolioAdjustment 调账（需要 folios:adjust 权限），已结账的账单不能调账
+func AddFolioAdjustment(c *gin.Context) {
+	billID, err := strconv.Atoi(c.Param("bill_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的账单号",
+		})
+		return
+	}
+
+	var req FolioAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	username, _ := c.Get("username")
+	entry := models.FolioEntry{
+		BillID:      billID,
+		EntryType:   models.FolioAdjustment,
+		Description: req.Description,
+		Quantity:    1,
+		UnitPrice:   req.Amount,
+		Amount:      req.Amount,
+		Operator:    fmt.Sprint(username),
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		checkin, err := loadCheckinOperation(tx, billID)
+		if err != nil {
+			return err
+		}
+		settled, err := folioSettled(tx, billID)
+		if err != nil {
+			return err
+		}
+		if settled {
+			return conflictf("账单 %d 已结账，不能调账", billID)
+		}
+
+		entry.RoomID = checkin.RoomID
+		return postFolioEntry(tx, &entry)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "账单不存在",
+			})
+			return
+		}
+		respondBookingError(c, err, "调账失败")
+		return
+	}
+	middleware.SetAuditChange(c, nil, entry)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "调账成功",
+		"data":    entry,
+	})
+}
+
+// LoadFolio 加载账单明细并计算余额；未结账时按当前时间计算未入账的房费和空调费
+func LoadFolio(db *gorm.DB, billID int) (*Folio, error) {
+	checkin, err := loadCheckinOperation(db, billID)
+	if err != nil {
+		return nil, err
+	}
+	stay, err := billStay(db, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	folio := &Folio{
+		BillID:     billID,
+		RoomID:     stay.RoomID,
+		ClientID:   checkin.ClientID,
+		ClientName: checkin.ClientName,
+	}
+	if err := db.Where("bill_id = ?", billID).Order("id ASC").Find(&folio.Entries).Error; err != nil {
+		return nil, err
+	}
+
+	for _, entry := range folio.Entries {
+		if entry.Amount >= 0 {
+			folio.Charges += entry.Amount
+		} else {
+			folio.Credits -= entry.Amount
+		}
+		if entry.EntryType == models.FolioSettlement {
+			folio.Settled = true
+		}
+	}
+	folio.Charges = roundMoney(folio.Charges)
+	folio.Credits = roundMoney(folio.Credits)
+	folio.Balance = roundMoney(folio.Charges - folio.Credits)
+	folio.Projected = folio.Balance
+
+	if !folio.Settled {
+		charges, err := ComputeStayCharges(db, stay, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		folio.Pending = charges.Entries(stay)
+		for _, entry := range folio.Pending {
+			folio.Projected += entry.Amount
+		}
+		folio.Projected = roundMoney(folio.Projected)
+	}
+
+	return folio, nil
+}
+
+// ComputeStayCharges 计算入住到now为止未入账的房费和空调费，stay为账单当前所在房间的入住或换入记录
+func ComputeStayCharges(db *gorm.DB, stay models.RoomOperation, now time.Time) (StayCharges, error) {
+	days := int(now.Sub(stay.CheckinTime).Hours()/24) + 1
+	if days < 1 {
+		days = 1
+	}
+
+	var posted float64
+	if err := db.Model(&models.FolioEntry{}).
+		Where("bill_id = ? AND entry_type = ?", stay.BillID, models.FolioRoomCharge).
+		Select("COALESCE(SUM(quantity), 0)").Scan(&posted).Error; err != nil {
+		return StayCharges{}, err
+	}
+	nights := days - int(posted)
+	if nights < 0 {
+		nights = 0
+	}
+
+	acUsage, err := BillACUsage(db, stay.BillID)
+	if err != nil {
+		return StayCharges{}, fmt.Errorf("计算房间 %d 账单 %d 的空调费用失败: %w", stay.RoomID, stay.BillID, err)
+	}
+
+	return StayCharges{
+		Days:     days,
+		Nights:   nights,
+		RoomCost: roundMoney(float64(nights) * float64(stay.DailyRate)),
+		ACCost:   GetScheduler().Tariff().Settle(acUsage),
+	}, nil
+}
+
+// RoomEntry 生成未入账的房费明细，没有未入账的晚数时返回false
+func (s StayCharges) RoomEntry(stay models.RoomOperation) (models.FolioEntry, bool) {
+	if s.Nights <= 0 {
+		return models.FolioEntry{}, false
+	}
+	return models.FolioEntry{
+		BillID:      stay.BillID,
+		RoomID:      stay.RoomID,
+		EntryType:   models.FolioRoomCharge,
+		Description: fmt.Sprintf("房间 %d 房费 %d晚", stay.RoomID, s.Nights),
+		Quantity:    float64(s.Nights),
+		UnitPrice:   float64(stay.DailyRate),
+		Amount:      s.RoomCost,
+		Operator:    folioSystemOperator,
+	}, true
+}
+
+// Entries 生成未入账的房费和空调费明细，没有使用空调时不生成空调费明细
+func (s StayCharges) Entries(stay models.RoomOperation) []models.FolioEntry {
+	var entries []models.FolioEntry
+	if entry, ok := s.RoomEntry(stay); ok {
+		entries = append(entries, entry)
+	}
+	if s.ACCost > 0 {
+		entries = append(entries, models.FolioEntry{
+			BillID:      stay.BillID,
+			RoomID:      stay.RoomID,
+			EntryType:   models.FolioACCharge,
+			Description: "空调使用费",
+			Quantity:    1,
+			UnitPrice:   s.ACCost,
+			Amount:      s.ACCost,
+			Operator:    folioSystemOperator,
+		})
+	}
+	return entries
+}
+
+// settleFolio 结账：入账后按余额记一笔结账明细，使余额归零，返回结账前的余额
+// 余额为正表示向客人收款，为负表示退还押金余额
+func settleFolio(tx *gorm.DB, billID, roomID int) (float64, error) {
+	settled, err := folioSettled(tx, billID)
+	if err != nil {
+		return 0, err
+	}
+	if settled {
+		return 0, conflictf("账单 %d 已结账", billID)
+	}
+
+	var balance float64
+	if err := tx.Model(&models.FolioEntry{}).Where("bill_id = ?", billID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&balance).Error; err != nil {
+		return 0, err
+	}
+	balance = roundMoney(balance)
+
+	description := "结账"
+	switch {
+	case balance > 0:
+		description = "结账收款"
+	case balance < 0:
+		description = "退还押金余额"
+	}
+
+	settlement := models.FolioEntry{
+		BillID:      billID,
+		RoomID:      roomID,
+		EntryType:   models.FolioSettlement,
+		Description: description,
+		Quantity:    1,
+		UnitPrice:   -balance,
+		Amount:      -balance,
+		Operator:    folioSystemOperator,
+	}
+	if err := postFolioEntry(tx, &settlement); err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// postFolioEntry 保存账单明细，金额保留两位小数
+func postFolioEntry(db *gorm.DB, entry *models.FolioEntry) error {
+	entry.Amount = roundMoney(entry.Amount)
+	return db.Create(entry).Error
+}
+
+// folioSettled 账单是否已结账
+func folioSettled(db *gorm.DB, billID int) (bool, error) {
+	var count int64
+	if err := db.Model(&models.FolioEntry{}).
+		Where("bill_id = ? AND entry_type = ?", billID, models.FolioSettlement).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// loadCheckinOperation 根据账单号查找入住记录
+func loadCheckinOperation(db *gorm.DB, billID int) (models.RoomOperation, error) {
+	var checkin models.RoomOperation
+	err := db.Where("bill_id = ? AND operation_type = ?", billID, "checkin").First(&checkin).Error
+	return checkin, err
+}
+
+// roundMoney 金额保留两位小数
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
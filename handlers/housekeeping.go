@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// roomStateTransitions 客房部可以手动修改的房态，入住状态只能通过入住、退房和换房改变
+var roomStateTransitions = map[int][]int{
+	models.RoomVacantClean:  {models.RoomVacantDirty, models.RoomOutOfOrder, models.RoomOutOfService},
+	models.RoomVacantDirty:  {models.RoomVacantClean, models.RoomOutOfOrder, models.RoomOutOfService},
+	models.RoomOutOfOrder:   {models.RoomVacantClean, models.RoomVacantDirty},
+	models.RoomOutOfService: {models.RoomVacantClean, models.RoomVacantDirty},
+}
+
+// UpdateRoomStateRequest 修改房态请求结构
+type UpdateRoomStateRequest struct {
+	State  *int   `json:"state" binding:"required"` // 目标房态，0为合法值，因此使用指针
+	Reason string `json:"reason"`                   // 维修停用/暂停使用时必填
+}
+
+// RoomStateText 将房态转换为中文描述
+func RoomStateText(state int) string {
+	switch state {
+	case models.RoomVacantClean:
+		return "空房已清扫"
+	case models.RoomOccupied:
+		return "已入住"
+	case models.RoomVacantDirty:
+		return "空房待清扫"
+	case models.RoomOutOfOrder:
+		return "维修停用"
+	case models.RoomOutOfService:
+		return "暂停使用"
+	default:
+		return "未知"
+	}
+}
+
+// GetHousekeepingRooms 获取客房部房态列表（需要 rooms:housekeeping 权限），可按state筛选，并统计各房态的房间数
+func GetHousekeepingRooms(c *gin.Context) {
+	query := database.DB.Order("room_id ASC")
+	if state := c.Query("state"); state != "" {
+		value, err := strconv.Atoi(state)
+		if err != nil || RoomStateText(value) == "未知" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的房态",
+			})
+			return
+		}
+		query = query.Where("state = ?", value)
+	}
+
+	var rooms []models.RoomInfo
+	if err := query.Find(&rooms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取房态失败",
+		})
+		return
+	}
+
+	summary := make(map[string]int)
+	for _, room := range rooms {
+		summary[RoomStateText(room.State)]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取房态成功",
+		"rooms":   rooms,
+		"summary": summary,
+	})
+}
+
+// MarkRoomClean 清扫完成（需要 rooms:housekeeping 权限）：待清扫、维修停用或暂停使用的房间恢复为可出售的空房
+func MarkRoomClean(c *gin.Context) {
+	updateRoomState(c, models.RoomVacantClean, "")
+}
+
+// UpdateRoomState 修改房态（需要 rooms:housekeeping 权限），维修停用和暂停使用需要填写原因
+func UpdateRoomState(c *gin.Context) {
+	var req UpdateRoomStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if _, ok := roomStateTransitions[*req.State]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的房态，入住状态只能通过办理入住修改",
+		})
+		return
+	}
+	if (*req.State == models.RoomOutOfOrder || *req.State == models.RoomOutOfService) && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "维修停用和暂停使用需要填写原因",
+		})
+		return
+	}
+
+	updateRoomState(c, *req.State, req.Reason)
+}
+
+// updateRoomState 按房态转换规则修改房间状态，条件更新防止与入住、退房并发修改
+func updateRoomState(c *gin.Context, state int, reason string) {
+	roomID, err := strconv.Atoi(c.Param("room_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的房间ID",
+		})
+		return
+	}
+
+	var room, before models.RoomInfo
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("room_id = ?", roomID).First(&room).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRoomNotFound
+			}
+			return err
+		}
+		before = room
+		if !roomStateAllowed(room.State, state) {
+			return conflictf("房间 %d 当前为%s，不能改为%s", roomID, RoomStateText(room.State), RoomStateText(state))
+		}
+
+		now := time.Now()
+		result := tx.Model(&models.RoomInfo{}).
+			Where("room_id = ? AND state = ?", roomID, room.State).
+			Updates(map[string]interface{}{
+				"state":        state,
+				"state_reason": reason,
+				"state_time":   now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return conflictf("房间 %d 的状态已变化，请刷新后重试", roomID)
+		}
+		room.State = state
+		room.StateReason = reason
+		room.StateTime = now
+		return nil
+	})
+	if err != nil {
+		respondBookingError(c, err, "修改房态失败")
+		return
+	}
+	middleware.SetAuditChange(c, roomAuditSummary(before), roomAuditSummary(room))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "修改房态成功",
+		"room":       room,
+		"state_text": RoomStateText(room.State),
+	})
+}
+
+// roomStateAllowed 房态能否从from手动改为to
+func roomStateAllowed(from, to int) bool {
+	for _, state := range roomStateTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRoomSellable 检查房间当前能否办理入住：只有已清扫的空房可以出售
+func checkRoomSellable(room models.RoomInfo) error {
+	switch room.State {
+	case models.RoomVacantClean:
+		return nil
+	case models.RoomOccupied:
+		return conflictf("房间 %d 已被占用", room.RoomID)
+	default:
+		return conflictf("房间 %d %s，暂不可入住", room.RoomID, RoomStateText(room.State))
+	}
+}
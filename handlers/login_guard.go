@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// loginPolicy 登录失败的锁定规则：连续失败达到freeAttempts次后锁定base，之后每次失败锁定时间翻倍，最长max
+type loginPolicy struct {
+	freeAttempts int
+	base         time.Duration
+	max          time.Duration
+}
+
+// loginPolicies 按用户名和按IP的锁定规则，同一IP后面可能有多个用户，阈值更宽松
+var loginPolicies = map[string]loginPolicy{
+	models.LoginThrottleUsername: {freeAttempts: 5, base: 30 * time.Second, max: 15 * time.Minute},
+	models.LoginThrottleIP:       {freeAttempts: 20, base: 30 * time.Second, max: 15 * time.Minute},
+}
+
+// loginFailureWindow 超过该时间没有再失败时，失败次数重新计算
+const loginFailureWindow = time.Hour
+
+// lockDuration 第failures次连续失败后的锁定时间，未达到阈值时为0
+func (p loginPolicy) lockDuration(failures int) time.Duration {
+	if failures < p.freeAttempts {
+		return 0
+	}
+	exp := failures - p.freeAttempts
+	if exp >= 32 {
+		return p.max
+	}
+	d := time.Duration(float64(p.base) * math.Pow(2, float64(exp)))
+	if d > p.max {
+		return p.max
+	}
+	return d
+}
+
+// loginLockRemaining 用户名或IP处于锁定期时返回剩余的锁定时间（取较长者）
+func loginLockRemaining(username, ip string) (time.Duration, error) {
+	var throttles []models.LoginThrottle
+	now := time.Now()
+	if err := database.DB.
+		Where("((kind = ? AND key = ?) OR (kind = ? AND key = ?)) AND locked_until > ?",
+			models.LoginThrottleUsername, username, models.LoginThrottleIP, ip, now).
+		Find(&throttles).Error; err != nil {
+		return 0, err
+	}
+
+	var remaining time.Duration
+	for _, throttle := range throttles {
+		if d := throttle.LockedUntil.Sub(now); d > remaining {
+			remaining = d
+		}
+	}
+	return remaining, nil
+}
+
+// recordLoginFailure 记录一次登录失败；用户不存在和密码错误计入用户名和IP的失败次数，达到阈值后锁定
+func recordLoginFailure(username, ip, reason string) {
+	counts := reason == models.LoginFailureUnknownUser || reason == models.LoginFailureBadPassword
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.LoginFailure{Username: username, IP: ip, Reason: reason}).Error; err != nil {
+			return err
+		}
+		if !counts {
+			return nil
+		}
+		if err := pruneLoginThrottles(tx, time.Now()); err != nil {
+			return err
+		}
+		if err := countLoginFailure(tx, models.LoginThrottleUsername, username); err != nil {
+			return err
+		}
+		return countLoginFailure(tx, models.LoginThrottleIP, ip)
+	})
+	if err != nil {
+		log.Printf("记录登录失败失败: 用户名 %s, IP %s: %v", username, ip, err)
+	}
+}
+
+// countLoginFailure 增加用户名或IP的失败次数并按规则设置锁定截止时间
+func countLoginFailure(tx *gorm.DB, kind, key string) error {
+	now := time.Now()
+	var throttle models.LoginThrottle
+	if err := tx.Where(models.LoginThrottle{Kind: kind, Key: key}).FirstOrCreate(&throttle).Error; err != nil {
+		return err
+	}
+	if now.Sub(throttle.LastFailureAt) > loginFailureWindow {
+		throttle.Failures = 0
+		throttle.LockedRejects = 0
+	}
+	throttle.Failures++
+
+	var lockedUntil *time.Time
+	if d := loginPolicies[kind].lockDuration(throttle.Failures); d > 0 {
+		until := now.Add(d)
+		lockedUntil = &until
+		log.Printf("登录失败次数过多，锁定 %s=%s %v（连续失败 %d 次）", kind, key, d, throttle.Failures)
+	}
+	return tx.Model(&throttle).Updates(map[string]interface{}{
+		"failures":        throttle.Failures,
+		"last_failure_at": now,
+		"locked_until":    lockedUntil,
+		"locked_rejects":  throttle.LockedRejects,
+	}).Error
+}
+
+// countLockedReject 锁定期内的登录尝试只累加锁定中的用户名和IP记录上的计数，不写入登录失败记录
+// 锁定期间持续的请求不会使登录失败记录无限增长
+func countLockedReject(username, ip string) {
+	if err := database.DB.Model(&models.LoginThrottle{}).
+		Where("((kind = ? AND key = ?) OR (kind = ? AND key = ?)) AND locked_until > ?",
+			models.LoginThrottleUsername, username, models.LoginThrottleIP, ip, time.Now()).
+		UpdateColumn("locked_rejects", gorm.Expr("locked_rejects + 1")).Error; err != nil {
+		log.Printf("记录锁定期内的登录尝试失败: 用户名 %s, IP %s: %v", username, ip, err)
+	}
+}
+
+// pruneLoginThrottles 删除超过计数窗口没有再失败的记录，这些记录的失败次数已经过期，锁定也早已结束
+func pruneLoginThrottles(tx *gorm.DB, now time.Time) error {
+	return tx.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-loginFailureWindow), now).
+		Delete(&models.LoginThrottle{}).Error
+}
+
+// clearLoginThrottle 清除用户名或IP的失败次数和锁定
+func clearLoginThrottle(db *gorm.DB, kind, key string) error {
+	return db.Where("kind = ? AND key = ?", kind, key).Delete(&models.LoginThrottle{}).Error
+}
+
+// respondLoginLocked 返回429，Retry-After为剩余锁定秒数
+func respondLoginLocked(c *gin.Context, remaining time.Duration) {
+	seconds := int(math.Ceil(remaining.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("登录失败次数过多，请在 %d 秒后重试", seconds),
+		"retry_after": seconds,
+	})
+}
+
+// GetLoginLocks 获取登录失败计数和锁定列表（需要 users:manage 权限），可按kind筛选，locked=true只返回锁定中的记录
+func GetLoginLocks(c *gin.Context) {
+	query := database.DB.Order("updated_at DESC")
+	if kind := c.Query("kind"); kind != "" {
+		if _, ok := loginPolicies[kind]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "kind 必须是 username 或 ip",
+			})
+			return
+		}
+		query = query.Where("kind = ?", kind)
+	}
+	if locked := c.Query("locked"); locked != "" {
+		value, err := strconv.ParseBool(locked)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "locked 必须是 true 或 false",
+			})
+			return
+		}
+		if value {
+			query = query.Where("locked_until > ?", time.Now())
+		}
+	}
+
+	var throttles []models.LoginThrottle
+	if err := query.Find(&throttles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取登录锁定列表失败",
+		})
+		return
+	}
+
+	now := time.Now()
+	list := make([]gin.H, 0, len(throttles))
+	for _, throttle := range throttles {
+		list = append(list, gin.H{
+			"id":              throttle.ID,
+			"kind":            throttle.Kind,
+			"key":             throttle.Key,
+			"failures":        throttle.Failures,
+			"last_failure_at": throttle.LastFailureAt,
+			"locked_until":    throttle.LockedUntil,
+			"locked_rejects":  throttle.LockedRejects,
+			"locked":          throttle.LockedUntil != nil && throttle.LockedUntil.After(now),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取登录锁定列表成功",
+		"locks":   list,
+	})
+}
+
+// UnlockUser 解除用户的登录锁定并清除失败次数（需要 users:manage 权限）
+func UnlockUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "解除锁定失败",
+		})
+		return
+	}
+
+	var throttles []models.LoginThrottle
+	if err := database.DB.Where("kind = ? AND key = ?", models.LoginThrottleUsername, user.Username).Limit(1).Find(&throttles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "解除锁定失败",
+		})
+		return
+	}
+
+	if err := clearLoginThrottle(database.DB, models.LoginThrottleUsername, user.Username); err != nil {
+		log.Printf("解除用户 %d 的登录锁定失败: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "解除锁定失败",
+		})
+		return
+	}
+
+	if len(throttles) > 0 {
+		middleware.SetAuditChange(c, throttles[0], nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "解除锁定成功",
+		"user":    userInfo(user),
+	})
+}
+
+// DeleteLoginLock 按ID清除一条登录失败计数和锁定（需要 users:manage 权限），用于解除IP锁定
+func DeleteLoginLock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的锁定记录ID",
+		})
+		return
+	}
+
+	var throttle models.LoginThrottle
+	if err := database.DB.First(&throttle, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "锁定记录不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "解除锁定失败",
+		})
+		return
+	}
+
+	result := database.DB.Delete(&models.LoginThrottle{}, id)
+	if result.Error != nil {
+		log.Printf("清除登录锁定记录 %d 失败: %v", id, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "解除锁定失败",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "锁定记录不存在",
+		})
+		return
+	}
+
+	middleware.SetAuditChange(c, throttle, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "解除锁定成功",
+	})
+}
+
+// GetLoginFailures 获取登录失败记录（需要 users:manage 权限），可按username、ip和时间范围from/to筛选
+func GetLoginFailures(c *gin.Context) {
+	query := database.DB.Order("id DESC")
+	if username := c.Query("username"); username != "" {
+		query = query.Where("username = ?", username)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	query, ok := filterTimeRange(c, query, "created_at")
+	if !ok {
+		return
+	}
+	limit, ok := queryLimit(c)
+	if !ok {
+		return
+	}
+
+	var failures []models.LoginFailure
+	if err := query.Limit(limit).Find(&failures).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取登录失败记录失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "获取登录失败记录成功",
+		"failures": failures,
+	})
+}
+
+// 列表接口的默认和最大返回条数
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// queryLimit 读取limit查询参数，默认100条，最多1000条；参数无效时已写入响应
+func queryLimit(c *gin.Context) (int, bool) {
+	value := c.Query("limit")
+	if value == "" {
+		return defaultQueryLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "limit 必须是正整数",
+		})
+		return 0, false
+	}
+	if limit > maxQueryLimit {
+		limit = maxQueryLimit
+	}
+	return limit, true
+}
+
+// filterTimeRange 按from（含）和to（不含）查询参数筛选column，格式为RFC3339或日期（2006-01-02）
+// to为日期时包含当天；参数无效时已写入响应
+func filterTimeRange(c *gin.Context, query *gorm.DB, column string) (*gorm.DB, bool) {
+	for _, param := range []string{"from", "to"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			day, dayErr := time.ParseInLocation(DateLayout, value, time.Local)
+			if dayErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": param + " 的格式应为 RFC3339 或 " + DateLayout,
+				})
+				return nil, false
+			}
+			t = day
+			if param == "to" {
+				t = day.AddDate(0, 0, 1)
+			}
+		}
+		if param == "from" {
+			query = query.Where(column+" >= ?", t)
+		} else {
+			query = query.Where(column+" < ?", t)
+		}
+	}
+	return query, true
+}
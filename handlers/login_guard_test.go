@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// loginRequest 以指定用户名和密码发起登录请求，返回状态码
+func loginRequest(r *gin.Engine, username, password string) int {
+	body, _ := json.Marshal(LoginRequest{Username: username, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestLoginLockedAttemptsNotRecorded(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/login", Login)
+
+	if _, err := createUser("guest", "correct-password", models.RoleCustomer); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	// 超过计数窗口的旧记录在下一次登录失败时删除
+	stale := models.LoginThrottle{Kind: models.LoginThrottleIP, Key: "10.0.0.9", Failures: 3, LastFailureAt: time.Now().Add(-2 * loginFailureWindow)}
+	if err := database.DB.Create(&stale).Error; err != nil {
+		t.Fatalf("创建失败计数失败: %v", err)
+	}
+
+	policy := loginPolicies[models.LoginThrottleUsername]
+	for i := 0; i < policy.freeAttempts; i++ {
+		if code := loginRequest(r, "guest", "wrong-password"); code != http.StatusUnauthorized {
+			t.Fatalf("第 %d 次密码错误返回 %d，期望 401", i+1, code)
+		}
+	}
+
+	// 锁定期内的尝试返回429，只累加锁定记录上的计数，不写入登录失败记录
+	const rejected = 10
+	for i := 0; i < rejected; i++ {
+		if code := loginRequest(r, "guest", "correct-password"); code != http.StatusTooManyRequests {
+			t.Fatalf("锁定期内登录返回 %d，期望 429", code)
+		}
+	}
+
+	var failures int64
+	database.DB.Model(&models.LoginFailure{}).Count(&failures)
+	if failures != int64(policy.freeAttempts) {
+		t.Fatalf("有 %d 条登录失败记录，期望 %d", failures, policy.freeAttempts)
+	}
+
+	var throttle models.LoginThrottle
+	if err := database.DB.Where("kind = ? AND key = ?", models.LoginThrottleUsername, "guest").First(&throttle).Error; err != nil {
+		t.Fatalf("查询用户名的失败计数失败: %v", err)
+	}
+	if throttle.LockedRejects != rejected {
+		t.Fatalf("锁定期内被拒绝 %d 次，期望 %d", throttle.LockedRejects, rejected)
+	}
+
+	var count int64
+	database.DB.Model(&models.LoginThrottle{}).Where("id = ?", stale.ID).Count(&count)
+	if count != 0 {
+		t.Fatalf("超过计数窗口的失败计数没有被删除")
+	}
+}
+
+func TestUnlockUserRecordsClearedThrottle(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.AuditLogger())
+	r.POST("/login", Login)
+	r.POST("/users/:id/unlock", UnlockUser)
+
+	user, err := createUser("guest", "correct-password", models.RoleCustomer)
+	if err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	for i := 0; i < loginPolicies[models.LoginThrottleUsername].freeAttempts; i++ {
+		loginRequest(r, "guest", "wrong-password")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users/"+strconv.Itoa(user.ID)+"/unlock", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("解除锁定返回 %d: %s", w.Code, w.Body.String())
+	}
+	if code := loginRequest(r, "guest", "correct-password"); code != http.StatusOK {
+		t.Fatalf("解除锁定后登录返回 %d，期望 200", code)
+	}
+
+	// 审计日志记录被清除的失败计数
+	var entry models.AuditLog
+	if err := database.DB.Where("route = ?", "/users/:id/unlock").First(&entry).Error; err != nil {
+		t.Fatalf("查询审计日志失败: %v", err)
+	}
+	if entry.EntityType != models.AuditEntityUser || !strings.Contains(entry.Before, `"Key":"guest"`) || entry.After != "" {
+		t.Fatalf("审计日志不正确: %+v", entry)
+	}
+}
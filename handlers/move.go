@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// MoveRoomRequest 换房请求结构
+type MoveRoomRequest struct {
+	ToRoomID  int  `json:"to_room_id" binding:"required"` // 换入的空房
+	RestoreAC bool `json:"restore_ac"`                    // 原房间空调开着时，是否在新房间按原来的设置开启空调
+}
+
+// MoveRoom 换房：在住客人换到另一间空房，沿用原账单号
+// 原房间已入住的房费按原房间的每日房费入账，之后按新房间的每日房费计算；原房间的空调关机
+func MoveRoom(c *gin.Context) {
+	fromRoomID, err := strconv.Atoi(c.Param("room_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的房间ID",
+		})
+		return
+	}
+
+	var req MoveRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.ToRoomID == fromRoomID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "不能换到同一房间",
+		})
+		return
+	}
+
+	// 没有权限时只能为自己的房间换房
+	userID, _ := c.Get("user_id")
+	clientID := ""
+	if !middleware.HasPermission(c, models.PermRoomsModify) {
+		clientID = strconv.Itoa(userID.(int))
+	}
+
+	fromRoom, toRoom, err := loadMoveRooms(fromRoomID, req.ToRoomID, clientID)
+	if err != nil {
+		respondBookingError(c, err, "换房失败")
+		return
+	}
+
+	// 在事务外计算原房间未入账的房费：计算空调费需要获取调度器锁，
+	// 而事务持有数据库写锁，调度器在持有调度器锁时会写入空调状态，两者加锁顺序相反
+	stay, err := currentStay(database.DB, fromRoomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "无法找到入住记录",
+		})
+		return
+	}
+	now := time.Now()
+	charges, err := ComputeStayCharges(database.DB, stay, now)
+	if err != nil {
+		log.Printf("换房失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "计算费用失败",
+		})
+		return
+	}
+
+	// 检查预订、更新两个房间、房费入账和保存换房记录在同一个事务中完成
+	var moveIn models.RoomOperation
+	var movedNights int
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 检查新房间在剩余的入住期间没有被预订
+		if err := checkRoomBookable(tx, toRoom, dateOf(now), occupiedUntil(fromRoom), 0); err != nil {
+			return err
+		}
+
+		// 条件更新：原房间仍由该客人在住、新房间仍为空房时才能换房
+		result := tx.Model(&models.RoomInfo{}).
+			Where("room_id = ? AND state = ? AND client_id = ?", fromRoomID, models.RoomOccupied, fromRoom.ClientID).
+			Updates(map[string]interface{}{
+				"client_id":     "",
+				"client_name":   "",
+				"checkin_time":  time.Time{},
+				"checkout_time": time.Time{},
+				"state":         models.RoomVacantDirty, // 换出后待清扫
+				"state_reason":  "",
+				"state_time":    now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return conflictf("房间 %d 已退房", fromRoomID)
+		}
+
+		result = tx.Model(&models.RoomInfo{}).
+			Where("room_id = ? AND state = ?", req.ToRoomID, models.RoomVacantClean).
+			Updates(map[string]interface{}{
+				"client_id":     fromRoom.ClientID,
+				"client_name":   fromRoom.ClientName,
+				"checkin_time":  fromRoom.CheckinTime,
+				"checkout_time": fromRoom.CheckoutTime,
+				"state":         models.RoomOccupied,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return conflictf("房间 %d 已被占用", req.ToRoomID)
+		}
+
+		// 原房间已入住的房费入账
+		if entry, ok := charges.RoomEntry(stay); ok {
+			if err := postFolioEntry(tx, &entry); err != nil {
+				return err
+			}
+			movedNights = charges.Nights
+		}
+
+		// 以同一账单号记录换出和换入操作
+		moveOut := models.RoomOperation{
+			RoomID:        fromRoomID,
+			BillID:        stay.BillID,
+			ClientID:      fromRoom.ClientID,
+			ClientName:    fromRoom.ClientName,
+			OperationType: "move_out",
+			OperationTime: now,
+			CheckinTime:   fromRoom.CheckinTime,
+			CheckoutTime:  now,
+			DailyRate:     fromRoom.DailyRate,
+			Deposit:       fromRoom.Deposit,
+			TotalCost:     float32(charges.RoomCost),
+			ActualDays:    charges.Nights,
+		}
+		if err := tx.Create(&moveOut).Error; err != nil {
+			return err
+		}
+
+		remaining := daysBetween(dateOf(fromRoom.CheckinTime), dateOf(fromRoom.CheckoutTime)) - charges.Days
+		if remaining < 0 {
+			remaining = 0
+		}
+		moveIn = models.RoomOperation{
+			RoomID:        req.ToRoomID,
+			BillID:        stay.BillID,
+			ClientID:      fromRoom.ClientID,
+			ClientName:    fromRoom.ClientName,
+			OperationType: "move_in",
+			OperationTime: now,
+			CheckinTime:   fromRoom.CheckinTime,
+			CheckoutTime:  fromRoom.CheckoutTime,
+			DailyRate:     toRoom.DailyRate,
+			Deposit:       fromRoom.Deposit,
+			TotalCost:     float32(remaining) * toRoom.DailyRate,
+			ActualDays:    remaining,
+		}
+		return tx.Create(&moveIn).Error
+	})
+	if err != nil {
+		respondBookingError(c, err, "换房失败")
+		return
+	}
+
+	acRestored := moveAirConditioner(fromRoomID, req.ToRoomID, moveIn.BillID, req.RestoreAC)
+	middleware.SetAuditChange(c,
+		gin.H{"room_id": fromRoomID, "bill_id": moveIn.BillID, "client_id": fromRoom.ClientID, "daily_rate": fromRoom.DailyRate},
+		gin.H{"room_id": req.ToRoomID, "bill_id": moveIn.BillID, "client_id": fromRoom.ClientID, "daily_rate": toRoom.DailyRate})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "换房成功",
+		"bill_id":       moveIn.BillID,
+		"from_room_id":  fromRoomID,
+		"to_room_id":    req.ToRoomID,
+		"checkin_time":  fromRoom.CheckinTime,
+		"checkout_time": fromRoom.CheckoutTime,
+		"daily_rate":    toRoom.DailyRate,
+		"moved_nights":  movedNights,
+		"ac_restored":   acRestored,
+	})
+}
+
+// loadMoveRooms 加载换房的原房间和新房间并检查状态：原房间需已入住（clientID不为空时需为该客人的房间），新房间需可出售
+// 只是提前检查，事务中的条件更新保证并发换房、退房时不会重复操作
+func loadMoveRooms(fromRoomID, toRoomID int, clientID string) (models.RoomInfo, models.RoomInfo, error) {
+	var fromRoom, toRoom models.RoomInfo
+	if err := database.DB.Where("room_id = ?", fromRoomID).First(&fromRoom).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fromRoom, toRoom, errRoomNotFound
+		}
+		return fromRoom, toRoom, err
+	}
+	if clientID != "" && fromRoom.ClientID != clientID {
+		return fromRoom, toRoom, errRoomNotFound
+	}
+	if fromRoom.State != models.RoomOccupied {
+		return fromRoom, toRoom, conflictf("房间 %d 未入住", fromRoomID)
+	}
+
+	if err := database.DB.Where("room_id = ?", toRoomID).First(&toRoom).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fromRoom, toRoom, errRoomNotFound
+		}
+		return fromRoom, toRoom, err
+	}
+	if err := checkRoomSellable(toRoom); err != nil {
+		return fromRoom, toRoom, err
+	}
+	return fromRoom, toRoom, nil
+}
+
+// moveAirConditioner 换房后关闭原房间开着的空调，restore为true时在新房间按原来的设置开启空调，返回是否已在新房间开启
+// 换房已完成，空调操作失败只记录日志
+func moveAirConditioner(fromRoomID, toRoomID, billID int, restore bool) bool {
+	lastOp, on, err := shutdownAirConditioner(fromRoomID, billID)
+	if err != nil {
+		log.Printf("换房时%v", err)
+	}
+	if !on || !restore {
+		return false
+	}
+
+	var toAC models.AirConditioner
+	if err := database.DB.Where("room_id = ?", toRoomID).First(&toAC).Error; err != nil {
+		log.Printf("换房时未找到房间 %d 的空调: %v", toRoomID, err)
+		return false
+	}
+	start := models.AirConditionerOperation{
+		BillID:          billID,
+		RoomID:          toRoomID,
+		AcID:            toAC.ID,
+		OperationState:  0,
+		Speed:           lastOp.Speed,
+		Mode:            lastOp.Mode,
+		TargetTemp:      lastOp.TargetTemp,
+		EnvironmentTemp: toAC.EnvironmentTemp,
+		CurrentTemp:     toAC.EnvironmentTemp,
+		SwitchCount:     1,
+	}
+	if err := applyACOperation(toAC, billID, &start); err != nil {
+		log.Printf("换房时开启房间 %d 的空调失败: %v", toRoomID, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 报表下载格式
+const (
+	ReportFormatXLSX = "xlsx"
+	ReportFormatCSV  = "csv"
+	ReportFormatHTML = "html"
+)
+
+// reportContentTypes 各下载格式的Content-Type
+var reportContentTypes = map[string]string{
+	ReportFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	ReportFormatCSV:  "text/csv; charset=utf-8",
+	ReportFormatHTML: "text/html; charset=utf-8",
+}
+
+// Report 与输出格式无关的报表内容：标题、头部信息、若干表格和汇总信息
+type Report struct {
+	Title    string
+	Sheet    string          // xlsx工作表名称
+	Meta     []ReportField   // 头部信息，如账单号、房间号
+	Sections []ReportSection // 表格
+	Summary  []ReportField   // 汇总信息
+}
+
+// ReportField 报表中的一项“名称: 值”信息
+type ReportField struct {
+	Label string
+	Value interface{}
+}
+
+// ReportSection 报表中的一个表格
+type ReportSection struct {
+	Title  string
+	Header []string
+	Rows   [][]interface{}
+}
+
+// WriteReport 按格式输出报表
+func WriteReport(w io.Writer, report *Report, format string) error {
+	switch format {
+	case ReportFormatXLSX:
+		return writeReportXLSX(w, report)
+	case ReportFormatCSV:
+		return writeReportCSV(w, report)
+	case ReportFormatHTML:
+		return writeReportHTML(w, report)
+	default:
+		return fmt.Errorf("不支持的报表格式: %s", format)
+	}
+}
+
+// writeReportXLSX 输出Excel报表：标题和头部信息在前，表格之间空两行，最后是汇总信息
+func writeReportXLSX(w io.Writer, report *Report) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := report.Sheet
+	if sheetName == "" {
+		sheetName = report.Title
+	}
+	f.SetSheetName("Sheet1", sheetName)
+
+	cell := func(col, row int) string {
+		name, _ := excelize.CoordinatesToCellName(col, row)
+		return name
+	}
+
+	row := 1
+	f.SetCellValue(sheetName, cell(1, row), report.Title)
+	for _, field := range report.Meta {
+		row++
+		f.SetCellValue(sheetName, cell(1, row), fmt.Sprintf("%s: %v", field.Label, field.Value))
+	}
+
+	row += 2
+	for _, section := range report.Sections {
+		f.SetCellValue(sheetName, cell(1, row), section.Title)
+		row++
+		for i, title := range section.Header {
+			f.SetCellValue(sheetName, cell(i+1, row), title)
+		}
+		row++
+		for _, values := range section.Rows {
+			for i, value := range values {
+				f.SetCellValue(sheetName, cell(i+1, row), value)
+			}
+			row++
+		}
+		row += 2
+	}
+
+	if len(report.Summary) > 0 {
+		f.SetCellValue(sheetName, cell(1, row), "汇总信息")
+		for _, field := range report.Summary {
+			row++
+			f.SetCellValue(sheetName, cell(1, row), fmt.Sprintf("%s: %v", field.Label, field.Value))
+		}
+	}
+
+	// 设置列宽
+	f.SetColWidth(sheetName, "A", "A", 8)
+	f.SetColWidth(sheetName, "B", "B", 20)
+	f.SetColWidth(sheetName, "C", "J", 12)
+
+	return f.Write(w)
+}
+
+// writeReportCSV 输出CSV报表，带UTF-8 BOM以便Excel正确识别中文
+func writeReportCSV(w io.Writer, report *Report) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{csvCell(report.Title)})
+	for _, field := range report.Meta {
+		cw.Write([]string{csvCell(field.Label), csvCell(field.Value)})
+	}
+
+	for _, section := range report.Sections {
+		cw.Write(nil)
+		cw.Write([]string{csvCell(section.Title)})
+		header := make([]string, len(section.Header))
+		for i, title := range section.Header {
+			header[i] = csvCell(title)
+		}
+		cw.Write(header)
+		for _, values := range section.Rows {
+			record := make([]string, len(values))
+			for i, value := range values {
+				record[i] = csvCell(value)
+			}
+			cw.Write(record)
+		}
+	}
+
+	if len(report.Summary) > 0 {
+		cw.Write(nil)
+		cw.Write([]string{"汇总信息"})
+		for _, field := range report.Summary {
+			cw.Write([]string{csvCell(field.Label), csvCell(field.Value)})
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell 将值转换为CSV单元格文本
+// 以 = + - @ 开头的文本会被Excel当作公式执行，前面加上单引号防止CSV公式注入；数值原样输出，负数不受影响
+func csvCell(value interface{}) string {
+	text, ok := value.(string)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	if text != "" && strings.ContainsRune("=+-@", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// reportHTMLTemplate HTML报表模板，可直接在浏览器中打印为PDF
+var reportHTMLTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #999; padding: 4px 8px; text-align: left; }
+th { background: #eee; }
+dl { display: grid; grid-template-columns: max-content auto; gap: 4px 12px; }
+dt { font-weight: bold; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<dl>
+{{- range .Meta}}
+<dt>{{.Label}}</dt><dd>{{.Value}}</dd>
+{{- end}}
+</dl>
+{{- range .Sections}}
+<h2>{{.Title}}</h2>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Summary}}
+<h2>汇总信息</h2>
+<dl>
+{{- range .Summary}}
+<dt>{{.Label}}</dt><dd>{{.Value}}</dd>
+{{- end}}
+</dl>
+{{- end}}
+</body>
+</html>
+`))
+
+// writeReportHTML 输出HTML报表
+func writeReportHTML(w io.Writer, report *Report) error {
+	return reportHTMLTemplate.Execute(w, report)
+}
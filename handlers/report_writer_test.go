@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteReportCSVEscapesFormulas(t *testing.T) {
+	report := &Report{
+		Title: "酒店账单",
+		Meta: []ReportField{
+			{Label: "客户姓名", Value: `=HYPERLINK("http://example.com","x")`},
+			{Label: "房间号", Value: 101},
+		},
+		Sections: []ReportSection{{
+			Title:  "账单明细",
+			Header: []string{"说明", "金额"},
+			Rows: [][]interface{}{
+				{"+1+1", -50.0},
+				{"@SUM(A1)", 20.5},
+				{"-2+3", 0},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeReportCSV(&buf, report); err != nil {
+		t.Fatalf("输出CSV失败: %v", err)
+	}
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\ufeff")))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("解析CSV失败: %v", err)
+	}
+
+	want := [][]string{
+		{"酒店账单"},
+		{"客户姓名", `'=HYPERLINK("http://example.com","x")`},
+		{"房间号", "101"},
+		{"账单明细"},
+		{"说明", "金额"},
+		{"'+1+1", "-50"},
+		{"'@SUM(A1)", "20.5"},
+		{"'-2+3", "0"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("CSV内容为 %q，期望 %q", records, want)
+	}
+}
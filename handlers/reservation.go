@@ -0,0 +1,602 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// DateLayout 预订和房态日历使用的日期格式
+const DateLayout = "2006-01-02"
+
+const (
+	maxReservationNights = 90  // 单个预订最多的入住晚数
+	maxReservationAhead  = 365 // 最多提前预订的天数
+	maxCalendarDays      = 90  // 房态日历一次最多查询的天数
+)
+
+// CreateReservationRequest 创建预订请求结构
+// RoomID和RoomTypeID至少填写一个：填写RoomID预订指定房间，只填写RoomTypeID预订房间类型，入住时分配房间
+type CreateReservationRequest struct {
+	RoomTypeID int    `json:"room_type_id"`
+	RoomID     int    `json:"room_id"`
+	ClientName string `json:"client_name" binding:"required"`
+	StartDate  string `json:"start_date" binding:"required"` // 到店日期，格式 2006-01-02
+	EndDate    string `json:"end_date" binding:"required"`   // 离店日期，格式 2006-01-02
+}
+
+// ReservationCheckinRequest 预订入住请求结构
+type ReservationCheckinRequest struct {
+	RoomID int `json:"room_id"` // 只预订房间类型时可指定入住的房间，不指定时自动分配
+}
+
+// DayAvailability 某个房间类型某一天的房态
+type DayAvailability struct {
+	Date      string `json:"date"`
+	Total     int    `json:"total"`     // 房间总数，不含维修停用的房间
+	Occupied  int    `json:"occupied"`  // 在住房间数
+	Reserved  int    `json:"reserved"`  // 已预订未入住的数量
+	Available int    `json:"available"` // 可预订数量
+}
+
+// CreateReservation 创建预订
+func CreateReservation(c *gin.Context) {
+	var req CreateReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	startDate, endDate, err := parseStayDates(req.StartDate, req.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	reservation := models.Reservation{
+		ClientID:   strconv.Itoa(userID.(int)),
+		ClientName: req.ClientName,
+		RoomTypeID: req.RoomTypeID,
+		RoomID:     req.RoomID,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		State:      models.ReservationBooked,
+	}
+
+	if req.RoomID != 0 {
+		var room models.RoomInfo
+		if err := database.DB.Where("room_id = ?", req.RoomID).First(&room).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "房间不存在",
+			})
+			return
+		}
+		if req.RoomTypeID != 0 && req.RoomTypeID != room.RoomTypeID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "房间与房间类型不匹配",
+			})
+			return
+		}
+		reservation.RoomTypeID = room.RoomTypeID
+	} else {
+		if req.RoomTypeID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请指定房间或房间类型",
+			})
+			return
+		}
+		var roomType models.RoomType
+		if err := database.DB.First(&roomType, req.RoomTypeID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "房间类型不存在",
+			})
+			return
+		}
+	}
+
+	// 检查剩余数量和保存预订在同一个事务中完成，防止并发预订超出房间数量
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if reservation.RoomID != 0 {
+			// 预订指定房间：检查房间的预订和房间类型的剩余数量
+			var room models.RoomInfo
+			if err := tx.Where("room_id = ?", reservation.RoomID).First(&room).Error; err != nil {
+				return err
+			}
+			if err := checkRoomBookable(tx, room, startDate, endDate, 0); err != nil {
+				return err
+			}
+		} else {
+			// 只预订房间类型：检查每天的剩余数量
+			if err := checkTypeAvailable(tx, reservation.RoomTypeID, startDate, endDate, 0); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&reservation).Error
+	})
+	if err != nil {
+		respondBookingError(c, err, "创建预订失败")
+		return
+	}
+	middleware.SetAuditEntity(c, models.AuditEntityReservation, reservation.ID)
+	middleware.SetAuditChange(c, nil, reservation)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "预订成功",
+		"data":    reservation,
+	})
+}
+
+// GetMyReservations 获取我的预订
+func GetMyReservations(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var reservations []models.Reservation
+	if err := database.DB.Where("client_id = ?", strconv.Itoa(userID.(int))).Order("start_date ASC").Find(&reservations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取预订信息失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "获取我的预订成功",
+		"reservations": reservations,
+	})
+}
+
+// GetAllReservations 获取所有预订（需要 reservations:read 权限），可按状态、房间类型和日期筛选
+func GetAllReservations(c *gin.Context) {
+	query := database.DB.Model(&models.Reservation{})
+
+	if state := c.Query("state"); state != "" {
+		query = query.Where("state = ?", state)
+	}
+	if typeID := c.Query("room_type_id"); typeID != "" {
+		query = query.Where("room_type_id = ?", typeID)
+	}
+	if date := c.Query("date"); date != "" {
+		day, err := time.ParseInLocation(DateLayout, date, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "日期格式错误，应为 " + DateLayout,
+			})
+			return
+		}
+		// 查询覆盖该日期的预订
+		query = query.Where("start_date <= ? AND end_date > ?", day, day)
+	}
+
+	var reservations []models.Reservation
+	if err := query.Order("start_date ASC").Find(&reservations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取预订信息失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "获取所有预订成功",
+		"reservations": reservations,
+	})
+}
+
+// CancelReservation 取消预订，只能取消未入住的预订
+func CancelReservation(c *gin.Context) {
+	reservation, ok := loadOwnReservation(c)
+	if !ok {
+		return
+	}
+
+	if reservation.State != models.ReservationBooked {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "预订已入住或已取消，无法取消",
+		})
+		return
+	}
+
+	// 条件更新：预订在读取之后被并发入住或取消时不再修改
+	before := reservation
+	if err := updateBookedReservation(database.DB, &reservation, map[string]interface{}{
+		"state": models.ReservationCancelled,
+	}); err != nil {
+		respondBookingError(c, err, "取消预订失败")
+		return
+	}
+	middleware.SetAuditChange(c, before, reservation)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "取消预订成功",
+		"data":    reservation,
+	})
+}
+
+// CheckinReservation 预订入住：在预订的日期范围内将预订转换为入住，入住到预订的离店日期
+func CheckinReservation(c *gin.Context) {
+	reservation, ok := loadOwnReservation(c)
+	if !ok {
+		return
+	}
+
+	var req ReservationCheckinRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if reservation.State != models.ReservationBooked {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "预订已入住或已取消",
+		})
+		return
+	}
+
+	today := dateOf(time.Now())
+	if today.Before(reservation.StartDate) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "未到预订的入住日期: " + reservation.StartDate.Format(DateLayout),
+		})
+		return
+	}
+	if !today.Before(reservation.EndDate) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "预订已过期",
+		})
+		return
+	}
+
+	// 确定入住的房间
+	roomID := reservation.RoomID
+	if roomID != 0 && req.RoomID != 0 && req.RoomID != roomID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("该预订只能入住房间 %d", roomID),
+		})
+		return
+	}
+	if roomID == 0 {
+		roomID = req.RoomID
+	}
+
+	if roomID != 0 {
+		var room models.RoomInfo
+		if err := database.DB.Where("room_id = ?", roomID).First(&room).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "房间不存在",
+			})
+			return
+		}
+		if room.RoomTypeID != reservation.RoomTypeID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "房间与预订的房间类型不匹配",
+			})
+			return
+		}
+	}
+
+	// 分配房间、办理入住和更新预订状态在同一个事务中完成
+	before := reservation
+	var room models.RoomInfo
+	var roomOperation *models.RoomOperation
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if roomID != 0 {
+			if err := tx.Where("room_id = ?", roomID).First(&room).Error; err != nil {
+				return err
+			}
+			if err := checkRoomSellable(room); err != nil {
+				return err
+			}
+			if err := checkRoomBookable(tx, room, today, reservation.EndDate, reservation.ID); err != nil {
+				return err
+			}
+		} else {
+			found, err := findBookableRoom(tx, reservation.RoomTypeID, today, reservation.EndDate, reservation.ID)
+			if err != nil {
+				return err
+			}
+			room = *found
+		}
+
+		var err error
+		days := daysBetween(today, reservation.EndDate)
+		roomOperation, err = checkinRoom(tx, &room, reservation.ClientID, reservation.ClientName, days)
+		if err != nil {
+			return err
+		}
+
+		return updateBookedReservation(tx, &reservation, map[string]interface{}{
+			"state":   models.ReservationCheckedIn,
+			"room_id": room.RoomID,
+			"bill_id": roomOperation.BillID,
+		})
+	})
+	if err != nil {
+		respondBookingError(c, err, "入住失败")
+		return
+	}
+	middleware.SetAuditChange(c, before, reservation)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "入住成功",
+		"bill_id":        roomOperation.BillID,
+		"reservation_id": reservation.ID,
+		"room_id":        room.RoomID,
+		"client_name":    room.ClientName,
+		"checkin_time":   room.CheckinTime,
+		"checkout_time":  room.CheckoutTime,
+		"daily_rate":     room.DailyRate,
+		"deposit":        room.Deposit,
+		"total_cost":     roomOperation.TotalCost,
+	})
+}
+
+// GetAvailabilityCalendar 查询房态日历：每个房间类型每天的可预订数量
+// 查询参数 from/to 为日期范围[from, to)，默认从今天起30天；type_id 为空时返回所有房间类型
+func GetAvailabilityCalendar(c *gin.Context) {
+	from := dateOf(time.Now())
+	if value := c.Query("from"); value != "" {
+		day, err := time.ParseInLocation(DateLayout, value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "from 日期格式错误，应为 " + DateLayout,
+			})
+			return
+		}
+		from = day
+	}
+	to := from.AddDate(0, 0, 30)
+	if value := c.Query("to"); value != "" {
+		day, err := time.ParseInLocation(DateLayout, value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "to 日期格式错误，应为 " + DateLayout,
+			})
+			return
+		}
+		to = day
+	}
+	if !to.After(from) || daysBetween(from, to) > maxCalendarDays {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("日期范围无效，结束日期需晚于开始日期且最多查询%d天", maxCalendarDays),
+		})
+		return
+	}
+
+	query := database.DB.Order("id ASC")
+	if typeID := c.Query("type_id"); typeID != "" {
+		query = query.Where("id = ?", typeID)
+	}
+	var roomTypes []models.RoomType
+	if err := query.Find(&roomTypes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取房间类型信息失败",
+		})
+		return
+	}
+
+	calendar := make([]gin.H, 0, len(roomTypes))
+	for _, rt := range roomTypes {
+		days, err := roomTypeAvailability(database.DB, rt.ID, from, to, 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "获取房态信息失败",
+			})
+			return
+		}
+		calendar = append(calendar, gin.H{
+			"room_type_id": rt.ID,
+			"type":         rt.Type,
+			"days":         days,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取房态日历成功",
+		"data":    calendar,
+	})
+}
+
+// loadOwnReservation 加载URL中指定的预订，没有预订管理权限时只能操作自己的预订；失败时已写入响应
+func loadOwnReservation(c *gin.Context) (models.Reservation, bool) {
+	var reservation models.Reservation
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的预订ID",
+		})
+		return reservation, false
+	}
+
+	userID, _ := c.Get("user_id")
+
+	query := database.DB.Where("id = ?", id)
+	if !middleware.HasPermission(c, models.PermReservationsManage) {
+		query = query.Where("client_id = ?", strconv.Itoa(userID.(int)))
+	}
+	if err := query.First(&reservation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "预订不存在或您无权操作此预订",
+		})
+		return reservation, false
+	}
+	return reservation, true
+}
+
+// parseStayDates 解析并校验入住日期范围
+func parseStayDates(start, end string) (time.Time, time.Time, error) {
+	startDate, err := time.ParseInLocation(DateLayout, start, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("到店日期格式错误，应为 %s", DateLayout)
+	}
+	endDate, err := time.ParseInLocation(DateLayout, end, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("离店日期格式错误，应为 %s", DateLayout)
+	}
+
+	today := dateOf(time.Now())
+	if startDate.Before(today) {
+		return time.Time{}, time.Time{}, errors.New("到店日期不能早于今天")
+	}
+	if !endDate.After(startDate) {
+		return time.Time{}, time.Time{}, errors.New("离店日期必须晚于到店日期")
+	}
+	if daysBetween(startDate, endDate) > maxReservationNights {
+		return time.Time{}, time.Time{}, fmt.Errorf("单次预订最多%d晚", maxReservationNights)
+	}
+	if daysBetween(today, startDate) > maxReservationAhead {
+		return time.Time{}, time.Time{}, fmt.Errorf("最多提前%d天预订", maxReservationAhead)
+	}
+	return startDate, endDate, nil
+}
+
+// updateBookedReservation 更新仍为已预订状态的预订，并把更新同步到reservation；
+// 预订已被并发入住或取消时返回冲突错误
+func updateBookedReservation(db *gorm.DB, reservation *models.Reservation, updates map[string]interface{}) error {
+	result := db.Model(&models.Reservation{}).
+		Where("id = ? AND state = ?", reservation.ID, models.ReservationBooked).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return conflictf("预订 %d 已入住或已取消", reservation.ID)
+	}
+	return db.First(reservation, reservation.ID).Error
+}
+
+// checkRoomBookable 检查房间在[start, end)期间能否入住或被预订：
+// 房间没有重叠的预订、没有被在住客人占用，且房间类型每天都有剩余数量
+// excludeID 为需要忽略的预订（预订入住时忽略该预订本身）；不满足时返回冲突错误
+func checkRoomBookable(db *gorm.DB, room models.RoomInfo, start, end time.Time, excludeID int) error {
+	var conflict models.Reservation
+	err := db.Where("room_id = ? AND state = ? AND id <> ? AND start_date < ? AND end_date > ?",
+		room.RoomID, models.ReservationBooked, excludeID, end, start).First(&conflict).Error
+	if err == nil {
+		return conflictf("房间 %d 在 %s 至 %s 已被预订", room.RoomID,
+			conflict.StartDate.Format(DateLayout), conflict.EndDate.Format(DateLayout))
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if room.State == models.RoomOutOfOrder {
+		return conflictf("房间 %d 维修停用", room.RoomID)
+	}
+	if room.State == models.RoomOccupied && start.Before(occupiedUntil(room)) {
+		return conflictf("房间 %d 在该期间已被占用", room.RoomID)
+	}
+
+	return checkTypeAvailable(db, room.RoomTypeID, start, end, excludeID)
+}
+
+// checkTypeAvailable 检查房间类型在[start, end)期间每天是否都有剩余数量
+func checkTypeAvailable(db *gorm.DB, roomTypeID int, start, end time.Time, excludeID int) error {
+	days, err := roomTypeAvailability(db, roomTypeID, start, end, excludeID)
+	if err != nil {
+		return err
+	}
+	for _, day := range days {
+		if day.Available <= 0 {
+			return conflictf("该房间类型在 %s 已无可预订房间", day.Date)
+		}
+	}
+	return nil
+}
+
+// findBookableRoom 为只预订房间类型的预订分配一间在[start, end)期间可入住的空房
+func findBookableRoom(db *gorm.DB, roomTypeID int, start, end time.Time, excludeID int) (*models.RoomInfo, error) {
+	var rooms []models.RoomInfo
+	if err := db.Where("room_type_id = ? AND state = ?", roomTypeID, models.RoomVacantClean).Order("room_id ASC").Find(&rooms).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range rooms {
+		err := checkRoomBookable(db, rooms[i], start, end, excludeID)
+		if err == nil {
+			return &rooms[i], nil
+		}
+		var conflict *conflictError
+		if !errors.As(err, &conflict) {
+			return nil, err
+		}
+	}
+	return nil, conflictf("该房间类型暂无可入住的空房")
+}
+
+// roomTypeAvailability 计算房间类型在[from, to)期间每天的房态，维修停用的房间不计入房间总数
+func roomTypeAvailability(db *gorm.DB, roomTypeID int, from, to time.Time, excludeID int) ([]DayAvailability, error) {
+	var rooms []models.RoomInfo
+	if err := db.Where("room_type_id = ? AND state <> ?", roomTypeID, models.RoomOutOfOrder).Find(&rooms).Error; err != nil {
+		return nil, err
+	}
+
+	var reservations []models.Reservation
+	if err := db.Where("room_type_id = ? AND state = ? AND id <> ? AND start_date < ? AND end_date > ?",
+		roomTypeID, models.ReservationBooked, excludeID, to, from).Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+
+	days := make([]DayAvailability, 0, daysBetween(from, to))
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		availability := DayAvailability{
+			Date:  day.Format(DateLayout),
+			Total: len(rooms),
+		}
+		for _, room := range rooms {
+			if room.State == models.RoomOccupied && !day.Before(dateOf(room.CheckinTime)) && day.Before(occupiedUntil(room)) {
+				availability.Occupied++
+			}
+		}
+		for _, reservation := range reservations {
+			if !day.Before(reservation.StartDate) && day.Before(reservation.EndDate) {
+				availability.Reserved++
+			}
+		}
+		availability.Available = availability.Total - availability.Occupied - availability.Reserved
+		if availability.Available < 0 {
+			availability.Available = 0
+		}
+		days = append(days, availability)
+	}
+	return days, nil
+}
+
+// occupiedUntil 在住房间被占用到的日期（不含）：预计离店日期，已超过预计离店日期但未退房时至少占用到明天
+func occupiedUntil(room models.RoomInfo) time.Time {
+	until := dateOf(room.CheckoutTime)
+	tomorrow := dateOf(time.Now()).AddDate(0, 0, 1)
+	if until.Before(tomorrow) {
+		until = tomorrow
+	}
+	return until
+}
+
+// dateOf 返回时间所在日期的零点（本地时区）
+func dateOf(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// daysBetween 计算两个日期之间的天数
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// RoleRequest 创建或修改角色请求结构
+type RoleRequest struct {
+	Name        string   `json:"name"` // 创建时必填，修改时忽略
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// GetRoles 获取所有角色和可分配的权限列表（需要 roles:manage 权限）
+func GetRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := database.DB.Order("id ASC").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取角色列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "获取角色列表成功",
+		"roles":       roles,
+		"permissions": models.PermissionDescriptions,
+	})
+}
+
+// CreateRole 创建角色（需要 roles:manage 权限），只能授予自己拥有的权限
+func CreateRole(c *gin.Context) {
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "角色名称不能为空",
+		})
+		return
+	}
+
+	role := models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: pq.StringArray(req.Permissions),
+	}
+	if !checkRolePermissions(c, role) {
+		return
+	}
+
+	var count int64
+	if err := database.DB.Model(&models.Role{}).Where("name = ?", role.Name).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "创建角色失败",
+		})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "角色已存在",
+		})
+		return
+	}
+
+	if err := database.DB.Create(&role).Error; err != nil {
+		log.Printf("创建角色 %s 失败: %v", role.Name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "创建角色失败",
+		})
+		return
+	}
+	middleware.SetAuditEntity(c, models.AuditEntityRole, role.Name)
+	middleware.SetAuditChange(c, nil, role)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "角色创建成功",
+		"role":    role,
+	})
+}
+
+// UpdateRole 修改角色的说明和权限（需要 roles:manage 权限），立即对该角色的所有用户生效
+// administrator 角色始终拥有全部权限，不能修改
+func UpdateRole(c *gin.Context) {
+	name := c.Param("name")
+	if name == models.RoleAdministrator {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "不能修改 administrator 角色",
+		})
+		return
+	}
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var role models.Role
+	if err := database.DB.Where("name = ?", name).First(&role).Error; err != nil {
+		respondRoleNotFound(c, err)
+		return
+	}
+	before := role
+
+	role.Description = req.Description
+	role.Permissions = pq.StringArray(req.Permissions)
+	if !checkRolePermissions(c, role) {
+		return
+	}
+
+	if err := database.DB.Model(&role).Updates(map[string]interface{}{
+		"description": role.Description,
+		"permissions": role.Permissions,
+	}).Error; err != nil {
+		log.Printf("修改角色 %s 失败: %v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "修改角色失败",
+		})
+		return
+	}
+	middleware.SetAuditChange(c, before, role)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "角色修改成功",
+		"role":    role,
+	})
+}
+
+// DeleteRole 删除角色（需要 roles:manage 权限），内置角色和仍有用户使用的角色不能删除
+func DeleteRole(c *gin.Context) {
+	name := c.Param("name")
+	if name == models.RoleAdministrator || name == models.RoleCustomer {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "不能删除内置角色",
+		})
+		return
+	}
+
+	var role models.Role
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("name = ?", name).First(&role).Error; err != nil {
+			return err
+		}
+		var users int64
+		if err := tx.Model(&models.User{}).Where("identity = ?", name).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return conflictf("仍有 %d 个用户使用角色 %s", users, name)
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondRoleNotFound(c, err)
+			return
+		}
+		respondBookingError(c, err, "删除角色失败")
+		return
+	}
+	middleware.SetAuditChange(c, role, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "角色删除成功",
+	})
+}
+
+// checkRolePermissions 检查角色的权限字符串有效，且当前用户拥有这些权限；失败时已写入响应
+func checkRolePermissions(c *gin.Context, role models.Role) bool {
+	for _, permission := range role.Permissions {
+		if !models.ValidPermission(permission) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的权限: " + permission,
+			})
+			return false
+		}
+	}
+
+	caller, err := middleware.CurrentRole(c)
+	if err != nil {
+		log.Printf("加载角色权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "加载权限失败",
+		})
+		return false
+	}
+	if !roleCovers(caller, role) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "不能授予自己没有的权限",
+		})
+		return false
+	}
+	return true
+}
+
+// respondRoleNotFound 返回角色不存在或查询失败的响应
+func respondRoleNotFound(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "角色不存在",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "加载角色失败",
+	})
+}
@@ -1,18 +1,18 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
 
 	"bupt-hotel/database"
+	"bupt-hotel/middleware"
 	"bupt-hotel/models"
 )
 
@@ -23,10 +23,10 @@ type BookRoomRequest struct {
 	Days       int    `json:"days" binding:"required,min=1"` // 入住天数
 }
 
-// GetAvailableRooms 获取所有空房间
+// GetAvailableRooms 获取所有可出售的空房间（已清扫）
 func GetAvailableRooms(c *gin.Context) {
 	var rooms []models.RoomInfo
-	if err := database.DB.Where("state = ?", 0).Find(&rooms).Error; err != nil {
+	if err := database.DB.Where("state = ?", models.RoomVacantClean).Find(&rooms).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取房间信息失败",
 		})
@@ -39,7 +39,7 @@ func GetAvailableRooms(c *gin.Context) {
 	})
 }
 
-// GetAllRooms 获取所有房间（管理员权限）
+// GetAllRooms 获取所有房间（需要 rooms:read 权限）
 func GetAllRooms(c *gin.Context) {
 	var rooms []models.RoomInfo
 	if err := database.DB.Find(&rooms).Error; err != nil {
@@ -68,44 +68,135 @@ func BookRoom(c *gin.Context) {
 	// 获取用户信息
 	userID, _ := c.Get("user_id")
 	username, _ := c.Get("username")
+	middleware.SetAuditEntity(c, models.AuditEntityRoom, req.RoomID)
+
+	// 检查房间和预订、更新房间状态、保存入住记录在同一个事务中完成
+	var room, before models.RoomInfo
+	var roomOperation *models.RoomOperation
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 检查房间是否存在且为空房
+		if err := tx.Where("room_id = ?", req.RoomID).First(&room).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRoomNotFound
+			}
+			return err
+		}
+		before = room
+		if err := checkRoomSellable(room); err != nil {
+			return err
+		}
 
-	// 检查房间是否存在且为空房
-	var room models.RoomInfo
-	if err := database.DB.Where("room_id = ? AND state = ?", req.RoomID, 0).First(&room).Error; err != nil {
+		// 检查入住期间房间是否已被预订
+		checkinDate := dateOf(time.Now())
+		if err := checkRoomBookable(tx, room, checkinDate, checkinDate.AddDate(0, 0, req.Days), 0); err != nil {
+			return err
+		}
+
+		var err error
+		roomOperation, err = checkinRoom(tx, &room, strconv.Itoa(userID.(int)), req.ClientName, req.Days)
+		return err
+	})
+	if err != nil {
+		respondBookingError(c, err, "订房失败")
+		return
+	}
+	after := roomAuditSummary(room)
+	after["bill_id"] = roomOperation.BillID
+	middleware.SetAuditChange(c, roomAuditSummary(before), after)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "订房成功",
+		"bill_id":       roomOperation.BillID,
+		"room_id":       room.RoomID,
+		"client_name":   room.ClientName,
+		"checkin_time":  room.CheckinTime,
+		"checkout_time": room.CheckoutTime,
+		"daily_rate":    room.DailyRate,
+		"deposit":       room.Deposit,
+		"total_cost":    roomOperation.TotalCost,
+		"username":      username,
+	})
+}
+
+// errRoomNotFound 房间不存在
+var errRoomNotFound = errors.New("房间不存在")
+
+// conflictError 并发或业务冲突错误，接口返回409
+type conflictError struct {
+	msg string
+}
+
+func (e *conflictError) Error() string { return e.msg }
+
+// conflictf 创建冲突错误
+func conflictf(format string, args ...interface{}) error {
+	return &conflictError{msg: fmt.Sprintf(format, args...)}
+}
+
+// respondBookingError 返回订房/入住/退房事务的错误：房间不存在返回404，冲突返回409，其他错误返回500
+func respondBookingError(c *gin.Context, err error, message string) {
+	if errors.Is(err, errRoomNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{
-			"error": "房间不存在或已被占用",
+			"error": err.Error(),
 		})
 		return
 	}
 
+	var conflict *conflictError
+	if errors.As(err, &conflict) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": conflict.Error(),
+		})
+		return
+	}
+
+	log.Printf("%s: %v", message, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": message,
+	})
+}
+
+// checkinRoom 办理入住：更新房间为已入住并保存入住操作记录，返回入住操作记录（含账单号）
+// 现场订房和预订入住共用，需在事务中调用。房间只在仍为空房时更新，否则返回冲突错误
+func checkinRoom(tx *gorm.DB, room *models.RoomInfo, clientID, clientName string, days int) (*models.RoomOperation, error) {
 	// 更新房间信息
 	checkinTime := time.Now()
-	checkoutTime := checkinTime.AddDate(0, 0, req.Days)
-	totalCost := float32(req.Days) * room.DailyRate
+	checkoutTime := checkinTime.AddDate(0, 0, days)
+	totalCost := float32(days) * room.DailyRate
+
+	// 条件更新：只有房间仍为空房时才能入住，防止并发请求重复入住同一房间
+	result := tx.Model(&models.RoomInfo{}).
+		Where("room_id = ? AND state = ?", room.RoomID, models.RoomVacantClean).
+		Updates(map[string]interface{}{
+			"client_id":     clientID,
+			"client_name":   clientName,
+			"checkin_time":  checkinTime,
+			"checkout_time": checkoutTime,
+			"state":         models.RoomOccupied,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, conflictf("房间 %d 已被占用", room.RoomID)
+	}
 
-	room.ClientID = strconv.Itoa(userID.(int))
-	room.ClientName = req.ClientName
+	room.ClientID = clientID
+	room.ClientName = clientName
 	room.CheckinTime = checkinTime
 	room.CheckoutTime = checkoutTime
-	room.State = 1 // 已入住
-
-	if err := database.DB.Save(&room).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "订房失败",
-		})
-		return
-	}
+	room.State = models.RoomOccupied
 
 	// 生成账单号：时间戳+房间号
-	billIDStr := fmt.Sprintf("%d%03d", checkinTime.Unix(), req.RoomID)
+	billIDStr := fmt.Sprintf("%d%03d", checkinTime.Unix(), room.RoomID)
 	billID, _ := strconv.Atoi(billIDStr)
 
 	// 保存房间操作日志
 	roomOperation := models.RoomOperation{
-		RoomID:        req.RoomID,
+		RoomID:        room.RoomID,
 		BillID:        billID,
-		ClientID:      strconv.Itoa(userID.(int)),
-		ClientName:    req.ClientName,
+		ClientID:      clientID,
+		ClientName:    clientName,
 		OperationType: "checkin",
 		OperationTime: checkinTime,
 		CheckinTime:   checkinTime,
@@ -113,24 +204,31 @@ func BookRoom(c *gin.Context) {
 		DailyRate:     room.DailyRate,
 		Deposit:       room.Deposit,
 		TotalCost:     totalCost,
-		ActualDays:    req.Days,
+		ActualDays:    days,
 	}
 
-	if err := database.DB.Create(&roomOperation).Error; err != nil {
+	if err := tx.Create(&roomOperation).Error; err != nil {
+		return nil, fmt.Errorf("保存入住记录失败: %w", err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":       "订房成功",
-		"bill_id":       billID,
-		"room_id":       room.RoomID,
-		"client_name":   room.ClientName,
-		"checkin_time":  room.CheckinTime,
-		"checkout_time": room.CheckoutTime,
-		"daily_rate":    room.DailyRate,
-		"deposit":       room.Deposit,
-		"total_cost":    totalCost,
-		"username":      username,
-	})
+	// 押金记入账单，退房结账时抵扣费用
+	if room.Deposit > 0 {
+		deposit := models.FolioEntry{
+			BillID:      billID,
+			RoomID:      room.RoomID,
+			EntryType:   models.FolioDeposit,
+			Description: "押金",
+			Quantity:    1,
+			UnitPrice:   -float64(room.Deposit),
+			Amount:      -float64(room.Deposit),
+			Operator:    folioSystemOperator,
+		}
+		if err := postFolioEntry(tx, &deposit); err != nil {
+			return nil, fmt.Errorf("保存押金记录失败: %w", err)
+		}
+	}
+
+	return &roomOperation, nil
 }
 
 // CheckoutRoom 退房
@@ -146,14 +244,13 @@ func CheckoutRoom(c *gin.Context) {
 
 	// 获取用户信息
 	userID, _ := c.Get("user_id")
-	identity, _ := c.Get("identity")
 
 	// 查找房间
 	var room models.RoomInfo
-	query := database.DB.Where("room_id = ? AND state = ?", roomID, 1)
+	query := database.DB.Where("room_id = ?", roomID)
 
-	// 如果不是管理员，只能退自己的房间
-	if identity != "administrator" {
+	// 没有退房权限时只能退自己的房间
+	if !middleware.HasPermission(c, models.PermRoomsCheckout) {
 		query = query.Where("client_id = ?", strconv.Itoa(userID.(int)))
 	}
 
@@ -163,88 +260,130 @@ func CheckoutRoom(c *gin.Context) {
 		})
 		return
 	}
+	if room.State != models.RoomOccupied {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "房间未入住或已退房",
+		})
+		return
+	}
 
 	// 获取当前入住的账单号
-	var checkinOperation models.RoomOperation
-	if err := database.DB.Where("room_id = ? AND operation_type = 'checkin'", roomID).Order("operation_time DESC").First(&checkinOperation).Error; err != nil {
+	stay, err := currentStay(database.DB, roomID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "无法找到入住记录",
 		})
 		return
 	}
-	billID := checkinOperation.BillID
+	billID := stay.BillID
 
-	// 计算实际费用
-	actualDays := int(time.Since(room.CheckinTime).Hours()/24) + 1
-	if actualDays < 1 {
-		actualDays = 1
-	}
-	actualCost := float32(actualDays) * room.DailyRate
-	checkoutTime := time.Now()
-
-	// 获取空调操作记录
-	var acOperations []models.AirConditionerOperation
-	if err := database.DB.Where("room_id = ? AND bill_id = ?", roomID, billID).Find(&acOperations).Error; err != nil {
-		// 如果没有空调操作记录，继续退房流程
-	}
-
-	// 生成Excel文件
-	excelFile, err := generateACReportExcel(billID, roomID, acOperations)
-	if err != nil {
+	// 先关闭开着的空调，空调费用计算到关机为止
+	if _, _, err := shutdownAirConditioner(roomID, billID); err != nil {
+		log.Printf("退房失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "生成空调使用报告失败: " + err.Error(),
+			"error": "关闭空调失败",
 		})
 		return
 	}
 
-	// 保存房间操作日志（在重置房间状态之前）
-	roomOperation := models.RoomOperation{
-		RoomID:        roomID,
-		BillID:        billID,
-		ClientID:      room.ClientID,
-		ClientName:    room.ClientName,
-		OperationType: "checkout",
-		OperationTime: checkoutTime,
-		CheckinTime:   room.CheckinTime,
-		CheckoutTime:  checkoutTime,
-		DailyRate:     room.DailyRate,
-		Deposit:       room.Deposit,
-		TotalCost:     actualCost,
-		ActualDays:    actualDays,
-	}
-
-	if err := database.DB.Create(&roomOperation).Error; err != nil {
-		// 记录日志失败不影响主要业务流程，只记录错误
-	}
-
-	// 重置房间状态
-	room.ClientID = ""
-	room.ClientName = ""
-	room.CheckinTime = time.Time{}
-	room.CheckoutTime = time.Time{}
-	room.State = 0 // 空房
-
-	if err := database.DB.Save(&room).Error; err != nil {
+	// 计算未入账的房费和空调费
+	checkoutTime := time.Now()
+	charges, err := ComputeStayCharges(database.DB, stay, checkoutTime)
+	if err != nil {
+		log.Printf("退房失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "退房失败",
+			"error": "计算费用失败",
 		})
 		return
 	}
+	actualDays := charges.Days
+	acCost := charges.ACCost
+
+	// 重置房间状态、费用入账、保存退房记录和结账在同一个事务中完成
+	var actualCost, balance float64
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新：房间仍为本次入住的客人在住时才能退房，防止并发请求重复退房
+		result := tx.Model(&models.RoomInfo{}).
+			Where("room_id = ? AND state = ? AND client_id = ?", roomID, models.RoomOccupied, room.ClientID).
+			Updates(map[string]interface{}{
+				"client_id":     "",
+				"client_name":   "",
+				"checkin_time":  time.Time{},
+				"checkout_time": time.Time{},
+				"state":         models.RoomVacantDirty, // 退房后待清扫
+				"state_reason":  "",
+				"state_time":    checkoutTime,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return conflictf("房间 %d 已退房", roomID)
+		}
 
-	// 保存Excel文件到本地
-	filename := fmt.Sprintf("空调使用详单_%d_%d.xlsx", checkinOperation.BillID, roomID)
-	filePath := fmt.Sprintf("./reports/%s", filename)
+		// 房费和空调费入账，房费合计包括换房前已入账的房费
+		for _, entry := range charges.Entries(stay) {
+			entry := entry
+			if err := postFolioEntry(tx, &entry); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.FolioEntry{}).
+			Where("bill_id = ? AND entry_type = ?", billID, models.FolioRoomCharge).
+			Select("COALESCE(SUM(amount), 0)").Scan(&actualCost).Error; err != nil {
+			return err
+		}
 
-	// 确保reports目录存在
-	os.MkdirAll("./reports", 0755)
+		// 保存房间操作日志
+		roomOperation := models.RoomOperation{
+			RoomID:        roomID,
+			BillID:        billID,
+			ClientID:      room.ClientID,
+			ClientName:    room.ClientName,
+			OperationType: "checkout",
+			OperationTime: checkoutTime,
+			CheckinTime:   room.CheckinTime,
+			CheckoutTime:  checkoutTime,
+			DailyRate:     room.DailyRate,
+			Deposit:       room.Deposit,
+			TotalCost:     float32(actualCost + acCost),
+			ActualDays:    actualDays,
+		}
+		if err := tx.Create(&roomOperation).Error; err != nil {
+			return err
+		}
 
-	// 保存Excel文件到本地
-	if err := excelFile.SaveAs(filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "保存空调使用报告失败: " + err.Error(),
-		})
+		// 按余额结账
+		balance, err = settleFolio(tx, billID, roomID)
+		return err
+	})
+	if err != nil {
+		respondBookingError(c, err, "退房失败")
 		return
 	}
+	middleware.SetAuditChange(c, roomAuditSummary(room), gin.H{
+		"room_id":     roomID,
+		"state":       models.RoomVacantDirty,
+		"bill_id":     billID,
+		"actual_cost": actualCost,
+		"ac_cost":     acCost,
+		"balance":     balance,
+	})
+
+	// 生成空调使用详单并保存到本地，退房已完成，生成失败只记录日志，之后可通过下载接口重新生成
+	var folio *Folio
+	var acOperations []models.AirConditionerOperation
+	var filePath string
+	data, err := LoadBillData(billID)
+	if err != nil {
+		log.Printf("加载账单 %d 的报表数据失败: %v", billID, err)
+	} else {
+		folio = data.Folio
+		acOperations = data.ACOperations
+		if filePath, err = saveACReportFile(data); err != nil {
+			log.Printf("保存账单 %d 的空调使用详单失败: %v", billID, err)
+		}
+	}
 
 	// 返回成功响应
 	c.JSON(http.StatusOK, gin.H{
@@ -254,148 +393,24 @@ func CheckoutRoom(c *gin.Context) {
 			"bill_id":       billID,
 			"actual_cost":   actualCost,
 			"actual_days":   actualDays,
+			"ac_cost":       acCost,
+			"balance":       balance,
+			"folio":         folio,
 			"checkout_time": checkoutTime,
 			"report_file":   filePath,
+			"report_url":    fmt.Sprintf("/api/auth/folios/%d/ac-report", billID),
+			"invoice_url":   fmt.Sprintf("/api/auth/folios/%d/invoice", billID),
 			"ac_operations": acOperations,
 		},
 	})
 }
 
-// generateACReportExcel 生成空调使用报告Excel文件
-func generateACReportExcel(billID int, roomID int, acOperations []models.AirConditionerOperation) (*excelize.File, error) {
-	f := excelize.NewFile()
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
-
-	// 通过账单号查询空调详细记录数据
-	var acDetails []models.AirConditionerDetail
-	if err := database.DB.Where("bill_id = ?", billID).Order("created_at ASC").Find(&acDetails).Error; err != nil {
-		log.Printf("查询空调详细记录失败: %v", err)
-		// 如果查询失败，继续生成报告但不包含详细记录
-	}
-
-	// 设置工作表名称
-	sheetName := "空调使用详单"
-	f.SetSheetName("Sheet1", sheetName)
-
-	// 设置标题
-	f.SetCellValue(sheetName, "A1", "空调使用报告")
-	f.SetCellValue(sheetName, "A2", fmt.Sprintf("账单号: %d", billID))
-	f.SetCellValue(sheetName, "A3", fmt.Sprintf("房间号: %d", roomID))
-	f.SetCellValue(sheetName, "A4", fmt.Sprintf("生成时间: %s", time.Now().Format("2006-01-02 15:04:05")))
-
-	// 空调操作记录表头
-	f.SetCellValue(sheetName, "A6", "空调操作记录")
-	f.SetCellValue(sheetName, "A7", "序号")
-	f.SetCellValue(sheetName, "B7", "操作时间")
-	f.SetCellValue(sheetName, "C7", "操作类型")
-	f.SetCellValue(sheetName, "D7", "目标温度")
-	f.SetCellValue(sheetName, "E7", "风速")
-	f.SetCellValue(sheetName, "F7", "模式")
-
-	// 填充空调操作数据
-	row := 8
-	for i, op := range acOperations {
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), i+1)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), op.CreatedAt.Format("2006-01-02 15:04:05"))
-
-		// 将操作状态数字转换为中文描述
-		var operationDesc string
-		switch op.OperationState {
-		case 0:
-			operationDesc = "开机"
-		case 1:
-			operationDesc = "关机"
-		case 2:
-			operationDesc = "调温"
-		default:
-			operationDesc = "未知"
-		}
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), operationDesc)
-
-		// 目标温度除以10显示实际温度
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), float32(op.TargetTemp)/10.0)
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), op.Speed)
-		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), op.Mode)
-
-		row++
-	}
-
-	// 空调详细记录表头
-	row += 2
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "空调状态详细记录")
-	row++
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "序号")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), "记录时间")
-	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), "当前温度")
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), "目标温度")
-	f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), "风速")
-	f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), "模式")
-	f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), "当前费用")
-	f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), "总费用")
-	f.SetCellValue(sheetName, fmt.Sprintf("I%d", row), "运行时间")
-	f.SetCellValue(sheetName, fmt.Sprintf("J%d", row), "空调状态")
-	row++
-
-	// 填充空调详细记录数据
-	for i, detail := range acDetails {
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), i+1)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), detail.CreatedAt.Format("2006-01-02 15:04:05"))
-		// 当前温度除以10显示实际温度
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), float32(detail.CurrentTemp)/10.0)
-		// 目标温度除以10显示实际温度
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), float32(detail.TargetTemp)/10.0)
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), detail.Speed)
-		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), detail.Mode)
-		f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), detail.CurrentCost)
-		f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), detail.TotalCost)
-		f.SetCellValue(sheetName, fmt.Sprintf("I%d", row), detail.RunningTime)
-
-		// 将空调状态数字转换为中文描述
-		var statusDesc string
-		switch detail.ACStatus {
-		case 0:
-			statusDesc = "运行"
-		case 1:
-			statusDesc = "在等待序列"
-		case 2:
-			statusDesc = "关机回温"
-		case 3:
-			statusDesc = "达到目标温度回温"
-		default:
-			statusDesc = "未知"
-		}
-		f.SetCellValue(sheetName, fmt.Sprintf("J%d", row), statusDesc)
-
-		row++
-	}
-
-	// 添加汇总信息
-	row += 2
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "汇总信息")
-	row++
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("总操作次数: %d", len(acOperations)))
-	row++
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("详细记录条数: %d", len(acDetails)))
-	row++
-
-	// 设置列宽
-	f.SetColWidth(sheetName, "A", "A", 8)
-	f.SetColWidth(sheetName, "B", "B", 20)
-	f.SetColWidth(sheetName, "C", "J", 12)
-
-	return f, nil
-}
-
 // GetMyRooms 获取我的房间
 func GetMyRooms(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
 	var rooms []models.RoomInfo
-	if err := database.DB.Where("client_id = ? AND state = ?", strconv.Itoa(userID.(int)), 1).Find(&rooms).Error; err != nil {
+	if err := database.DB.Where("client_id = ? AND state = ?", strconv.Itoa(userID.(int)), models.RoomOccupied).Find(&rooms).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取房间信息失败",
 		})
@@ -483,6 +498,7 @@ func UpdateRoomType(c *gin.Context) {
 		}
 		return
 	}
+	before := roomType
 
 	// 更新房间类型信息
 	if updateData.Type != "" {
@@ -506,6 +522,8 @@ func UpdateRoomType(c *gin.Context) {
 		return
 	}
 
+	middleware.SetAuditChange(c, before, roomType)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "房间类型更新成功",
 		"room_type": roomType,
@@ -531,9 +549,9 @@ func GetRoomsByType(c *gin.Context) {
 		return
 	}
 
-	// 查询该类型的所有未入住房间
+	// 查询该类型的所有可出售房间
 	var rooms []models.RoomInfo
-	if err := database.DB.Where("room_type_id = ? AND state = ?", typeID, 0).Find(&rooms).Error; err != nil {
+	if err := database.DB.Where("room_type_id = ? AND state = ?", typeID, models.RoomVacantClean).Find(&rooms).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取房间信息失败",
 		})
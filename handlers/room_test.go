@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+// concurrentRequests 同时发起的请求数
+const concurrentRequests = 20
+
+// newRoomTestRouter 创建注册订房、退房和换房接口的路由，以请求头中的用户ID和角色代替JWT认证，未指定角色时为客户
+func newRoomTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		var userID int
+		fmt.Sscan(c.GetHeader("X-User-ID"), &userID)
+		c.Set("user_id", userID)
+		c.Set("username", fmt.Sprintf("user%d", userID))
+		identity := c.GetHeader("X-Identity")
+		if identity == "" {
+			identity = models.RoleCustomer
+		}
+		c.Set("identity", identity)
+		c.Next()
+	})
+	r.POST("/rooms/book", BookRoom)
+	r.POST("/rooms/:room_id/checkout", CheckoutRoom)
+	r.POST("/rooms/:room_id/move", MoveRoom)
+	return r
+}
+
+// hammer 并发发起n个请求，request(i)返回第i个请求，返回各状态码出现的次数
+func hammer(t *testing.T, r *gin.Engine, n int, request func(i int) *http.Request) map[int]int {
+	t.Helper()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	statuses := make(map[int]int)
+	for i := 0; i < n; i++ {
+		req := request(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			mu.Lock()
+			statuses[w.Code]++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return statuses
+}
+
+func bookRequest(userID, roomID int) *http.Request {
+	body, _ := json.Marshal(BookRoomRequest{RoomID: roomID, ClientName: fmt.Sprintf("guest%d", userID), Days: 1})
+	req := httptest.NewRequest(http.MethodPost, "/rooms/book", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", fmt.Sprint(userID))
+	return req
+}
+
+// checkOneWinner 检查恰好一个请求成功，其余请求返回409
+func checkOneWinner(t *testing.T, statuses map[int]int) {
+	t.Helper()
+
+	if statuses[http.StatusOK] != 1 || statuses[http.StatusConflict] != concurrentRequests-1 {
+		t.Fatalf("状态码分布为 %v，期望1个200和%d个409", statuses, concurrentRequests-1)
+	}
+}
+
+func TestBookRoomConcurrent(t *testing.T) {
+	setupTestDB(t)
+	r := newRoomTestRouter()
+
+	const roomID = 101
+	statuses := hammer(t, r, concurrentRequests, func(i int) *http.Request {
+		return bookRequest(i+1, roomID)
+	})
+	checkOneWinner(t, statuses)
+
+	var checkins int64
+	database.DB.Model(&models.RoomOperation{}).Where("room_id = ? AND operation_type = ?", roomID, "checkin").Count(&checkins)
+	if checkins != 1 {
+		t.Fatalf("房间 %d 有 %d 条入住记录，期望 1", roomID, checkins)
+	}
+
+	var deposits int64
+	database.DB.Model(&models.FolioEntry{}).Where("room_id = ? AND entry_type = ?", roomID, models.FolioDeposit).Count(&deposits)
+	if deposits != 1 {
+		t.Fatalf("房间 %d 有 %d 条押金记录，期望 1", roomID, deposits)
+	}
+}
+
+func TestCheckoutRoomConcurrent(t *testing.T) {
+	setupTestDB(t)
+	// 退房时将空调使用详单保存到当前目录下的 reports
+	t.Chdir(t.TempDir())
+	r := newRoomTestRouter()
+
+	// 前台同时为同一房间办理多次退房；客户本人重复退房时房间已不属于该客户，返回404
+	const roomID, userID, frontDeskID = 101, 1, 2
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, bookRequest(userID, roomID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("订房返回 %d: %s", w.Code, w.Body.String())
+	}
+
+	statuses := hammer(t, r, concurrentRequests, func(int) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/rooms/%d/checkout", roomID), nil)
+		req.Header.Set("X-User-ID", fmt.Sprint(frontDeskID))
+		req.Header.Set("X-Identity", "front_desk")
+		return req
+	})
+	checkOneWinner(t, statuses)
+
+	var checkouts int64
+	database.DB.Model(&models.RoomOperation{}).Where("room_id = ? AND operation_type = ?", roomID, "checkout").Count(&checkouts)
+	if checkouts != 1 {
+		t.Fatalf("房间 %d 有 %d 条退房记录，期望 1", roomID, checkouts)
+	}
+
+	var room models.RoomInfo
+	database.DB.First(&room, roomID)
+	if room.State != models.RoomVacantDirty {
+		t.Fatalf("退房后房间状态为 %d，期望 %d", room.State, models.RoomVacantDirty)
+	}
+}
+
+func TestCheckoutRoomShutsDownAC(t *testing.T) {
+	setupTestDB(t)
+	t.Chdir(t.TempDir())
+	r := newRoomTestRouter()
+
+	const roomID, userID = 101, 1
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, bookRequest(userID, roomID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("订房返回 %d: %s", w.Code, w.Body.String())
+	}
+	stay, err := currentStay(database.DB, roomID)
+	if err != nil {
+		t.Fatalf("查询入住记录失败: %v", err)
+	}
+
+	// 开机后调温：退房时空调仍开着，应先关机再计算费用
+	var ac models.AirConditioner
+	database.DB.Where("room_id = ?", roomID).First(&ac)
+	for _, state := range []int{0, 2} {
+		if err := database.DB.Create(&models.AirConditionerOperation{
+			BillID:         stay.BillID,
+			RoomID:         roomID,
+			AcID:           ac.ID,
+			OperationState: state,
+			Mode:           "cooling",
+			Speed:          "medium",
+			TargetTemp:     220,
+			SwitchCount:    1,
+		}).Error; err != nil {
+			t.Fatalf("保存空调操作失败: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/rooms/%d/checkout", roomID), nil)
+	req.Header.Set("X-User-ID", fmt.Sprint(userID))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("退房返回 %d: %s", w.Code, w.Body.String())
+	}
+
+	var shutdown models.AirConditionerOperation
+	if err := database.DB.Where("bill_id = ? AND operation_state = ?", stay.BillID, 1).First(&shutdown).Error; err != nil {
+		t.Fatalf("退房后没有关机记录: %v", err)
+	}
+	if shutdown.Mode != "cooling" || shutdown.TargetTemp != 220 || shutdown.SwitchCount != 2 {
+		t.Fatalf("关机记录的设置不正确: %+v", shutdown)
+	}
+	if _, on := activeACSettings(roomID, stay.BillID); on {
+		t.Fatalf("退房后房间 %d 的空调仍处于开机状态", roomID)
+	}
+}
+
+func TestMoveRoomKeepsACOffAfterSettingsChange(t *testing.T) {
+	setupTestDB(t)
+	r := newRoomTestRouter()
+
+	const fromRoomID, toRoomID, userID = 101, 102, 1
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, bookRequest(userID, fromRoomID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("订房返回 %d: %s", w.Code, w.Body.String())
+	}
+	stay, err := currentStay(database.DB, fromRoomID)
+	if err != nil {
+		t.Fatalf("查询入住记录失败: %v", err)
+	}
+
+	// 开机、关机后调温：换房时空调是关着的，不应再关机，也不应在新房间开机
+	var ac models.AirConditioner
+	database.DB.Where("room_id = ?", fromRoomID).First(&ac)
+	now := time.Now()
+	for i, state := range []int{0, 1, 2} {
+		if err := database.DB.Create(&models.AirConditionerOperation{
+			BillID:         stay.BillID,
+			RoomID:         fromRoomID,
+			AcID:           ac.ID,
+			OperationState: state,
+			Mode:           "cooling",
+			Speed:          "medium",
+			TargetTemp:     220,
+			CreatedAt:      now.Add(time.Duration(i) * time.Second),
+		}).Error; err != nil {
+			t.Fatalf("保存空调操作失败: %v", err)
+		}
+	}
+
+	body, _ := json.Marshal(gin.H{"to_room_id": toRoomID, "restore_ac": true})
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/rooms/%d/move", fromRoomID), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", fmt.Sprint(userID))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("换房返回 %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		ACRestored bool `json:"ac_restored"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.ACRestored {
+		t.Fatalf("空调已关机，换房后不应在新房间开机")
+	}
+
+	var operations int64
+	database.DB.Model(&models.AirConditionerOperation{}).Where("bill_id = ?", stay.BillID).Count(&operations)
+	if operations != 3 {
+		t.Fatalf("账单 %d 有 %d 条空调操作记录，期望 3", stay.BillID, operations)
+	}
+}
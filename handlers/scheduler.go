@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bupt-hotel/database"
+	"bupt-hotel/events"
 	"bupt-hotel/models"
 	"log"
 	"sort"
@@ -19,14 +20,41 @@ type ACScheduler struct {
 	bufferQueue  []*models.Scheduler       // 缓冲队列
 	warmingQueue []*models.Scheduler       // 回温队列
 
-	isRunning bool         // 调度器是否正在运行
-	stopChan  chan bool    // 停止信号
-	ticker    *time.Ticker // 定时器
+	isRunning bool      // 调度器是否正在运行
+	stopped   bool      // 调度器是否已被停止，停止后不再启动
+	stopChan  chan bool // 停止信号
+	ticker    Ticker    // 定时器
+
+	clock   Clock           // 调度器时钟
+	config  SchedulerConfig // 调度器参数
+	manual  bool            // 是否手动推进tick（使用虚拟时钟时为true）
+	persist bool            // 是否将空调状态保存到数据库
 
 	// 新增时间片相关属性
 	tickCount       int  // 当前tick计数
 	currentPriority int  // 当前时间片调度优先级，初始为0
 	firstACAdded    bool // 是否已添加第一个空调
+
+	policy  SchedulingPolicy // 缓冲队列调度策略
+	thermal ThermalModel     // 房间热模型
+	tariff  Tariff           // 空调计费规则
+	events  *events.Bus      // 调度事件总线
+
+	tickCharges map[int]float32 // ACID -> 本tick的计费金额，写入空调状态记录后清除
+}
+
+// DefaultTickInterval 默认tick间隔
+const DefaultTickInterval = 3 * time.Second
+
+// SchedulerOptions 调度器创建参数
+type SchedulerOptions struct {
+	Clock              Clock            // 时钟，为空时使用系统时钟；为*VirtualClock时需通过Step手动推进
+	Config             SchedulerConfig  // 调度器参数，未设置的字段使用默认值
+	Policy             SchedulingPolicy // 调度策略，为空时使用默认策略
+	Thermal            ThermalModel     // 房间热模型，为空时使用线性模型
+	Tariff             *Tariff          // 计费规则，为空时使用默认计费规则
+	Events             *events.Bus      // 调度事件总线，为空时不发布事件
+	DisablePersistence bool             // 不将空调状态写入数据库（测试和仿真使用）
 }
 
 var (
@@ -37,21 +65,130 @@ var (
 // GetScheduler 获取调度器单例
 func GetScheduler() *ACScheduler {
 	schedulerOnce.Do(func() {
-		schedulerInstance = &ACScheduler{
-			schedulers:      make(map[int]*models.Scheduler),
-			servingQueue:    make([]*models.Scheduler, 0),
-			bufferQueue:     make([]*models.Scheduler, 0),
-			warmingQueue:    make([]*models.Scheduler, 0),
-			isRunning:       false,
-			stopChan:        make(chan bool),
-			tickCount:       0,
-			currentPriority: 0,
-			firstACAdded:    false,
-		}
+		schedulerInstance = NewACScheduler(SchedulerOptions{Events: GetEventBus()})
 	})
 	return schedulerInstance
 }
 
+// NewACScheduler 创建独立的调度器实例
+func NewACScheduler(opts SchedulerOptions) *ACScheduler {
+	clock := opts.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	_, manual := clock.(*VirtualClock)
+
+	policy := opts.Policy
+	if policy == nil {
+		policy = priorityTimeSlicePolicy{}
+	}
+
+	thermal := opts.Thermal
+	if thermal == nil {
+		thermal = linearModel{}
+	}
+
+	tariff := DefaultTariff()
+	if opts.Tariff != nil {
+		tariff = *opts.Tariff
+	}
+
+	return &ACScheduler{
+		schedulers:      make(map[int]*models.Scheduler),
+		tickCharges:     make(map[int]float32),
+		servingQueue:    make([]*models.Scheduler, 0),
+		bufferQueue:     make([]*models.Scheduler, 0),
+		warmingQueue:    make([]*models.Scheduler, 0),
+		isRunning:       false,
+		stopChan:        make(chan bool),
+		clock:           clock,
+		config:          opts.Config.withDefaults(),
+		manual:          manual,
+		persist:         !opts.DisablePersistence,
+		tickCount:       0,
+		currentPriority: 0,
+		firstACAdded:    false,
+		policy:          policy,
+		thermal:         thermal,
+		tariff:          tariff,
+		events:          opts.Events,
+	}
+}
+
+// SetPolicy 设置调度策略，切换时清空时间片状态
+func (s *ACScheduler) SetPolicy(policy SchedulingPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policy = policy
+	s.resetTimeSlice()
+	log.Printf("调度策略已设置为: %s", policy.Name())
+}
+
+// SetThermalModel 设置房间热模型
+func (s *ACScheduler) SetThermalModel(thermal ThermalModel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.thermal = thermal
+	log.Printf("房间热模型已设置为: %s", thermal.Name())
+}
+
+// SetTariff 设置计费规则，新规则从下一个tick开始生效
+func (s *ACScheduler) SetTariff(tariff Tariff) error {
+	if err := tariff.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tariff = tariff
+	log.Printf("计费规则已设置: 风速费率=%v, 分时时段=%d个, 最低消费=%.2f",
+		tariff.SpeedRates, len(tariff.Periods), tariff.MinimumCharge)
+	return nil
+}
+
+// Tariff 返回当前计费规则
+func (s *ACScheduler) Tariff() Tariff {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.tariff
+}
+
+// SetConfig 设置调度器参数，tick间隔在调度器下次启动时生效
+func (s *ACScheduler) SetConfig(config SchedulerConfig) error {
+	config = config.withDefaults()
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.config = config
+	log.Printf("调度器参数已设置: 同时服务数=%d, 重排周期=%d, 时间片=%d, 回温温差=%d, tick间隔=%v",
+		config.MaxServing, config.SortInterval, config.TimeSlice, config.RewarmDelta, config.TickInterval)
+	return nil
+}
+
+// Config 返回当前调度器参数
+func (s *ACScheduler) Config() SchedulerConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.config
+}
+
+// PolicyName 返回当前调度策略名称
+func (s *ACScheduler) PolicyName() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.policy.Name()
+}
+
 // AddRequest 添加调度请求
 func (s *ACScheduler) AddRequest(scheduler *models.Scheduler) {
 	s.mu.Lock()
@@ -65,7 +202,8 @@ func (s *ACScheduler) AddRequest(scheduler *models.Scheduler) {
 		s.servingQueue = append(s.servingQueue, scheduler)
 		s.bufferQueue = append(s.bufferQueue, scheduler)
 		s.firstACAdded = true
-		if !s.isRunning {
+		s.publish(events.ACAdmitted{AC: s.acEvent(scheduler)})
+		if !s.isRunning && !s.manual {
 			go s.StartScheduler()
 		}
 		log.Printf("第一个空调加入服务队列: 空调ID %d", scheduler.ACID)
@@ -82,6 +220,7 @@ func (s *ACScheduler) AddRequest(scheduler *models.Scheduler) {
 				s.bufferQueue = append(s.bufferQueue, warmingScheduler)
 				// 从回温队列中移除
 				s.warmingQueue = append(s.warmingQueue[:i], s.warmingQueue[i+1:]...)
+				s.publish(events.ACRequeued{AC: s.acEvent(warmingScheduler)})
 				log.Printf("空调ID %d 从回温队列转移至缓冲队列", scheduler.ACID)
 				found = true
 				break
@@ -146,6 +285,7 @@ func (s *ACScheduler) RemoveRequest(acID int) {
 	for _, scheduler := range s.bufferQueue {
 		if scheduler.ACID == acID {
 			scheduler.ACState = 2
+			s.publish(events.ACShutdown{AC: s.acEvent(scheduler)})
 			log.Printf("空调ID %d 在缓冲队列中找到，状态设置为2（关机回温）", acID)
 			return
 		}
@@ -155,6 +295,7 @@ func (s *ACScheduler) RemoveRequest(acID int) {
 	for _, scheduler := range s.warmingQueue {
 		if scheduler.ACID == acID {
 			scheduler.ACState = 2
+			s.publish(events.ACShutdown{AC: s.acEvent(scheduler)})
 			log.Printf("空调ID %d 在回温队列中找到，状态设置为2（关机回温）", acID)
 			return
 		}
@@ -166,21 +307,26 @@ func (s *ACScheduler) RemoveRequest(acID int) {
 // StartScheduler 启动调度器
 func (s *ACScheduler) StartScheduler() {
 	s.mu.Lock()
-	if s.isRunning {
+	if s.isRunning || s.stopped {
 		s.mu.Unlock()
 		return
 	}
 	s.isRunning = true
-	s.ticker = time.NewTicker(3 * time.Second) // 每6秒执行一次（trick值为6）
+	s.ticker = s.clock.NewTicker(s.config.TickInterval)
+	tickInterval := s.config.TickInterval
 	s.mu.Unlock()
 
-	log.Println("空调调度器已启动，tick间隔为3秒")
+	log.Printf("空调调度器已启动，tick间隔为%v", tickInterval)
 
 	for {
 		select {
-		case <-s.ticker.C:
+		case <-s.ticker.C():
 			s.scheduleAirConditioners()
 		case <-s.stopChan:
+			s.mu.Lock()
+			s.ticker.Stop()
+			s.isRunning = false
+			s.mu.Unlock()
 			log.Println("空调调度器已停止")
 			return
 		}
@@ -188,6 +334,82 @@ func (s *ACScheduler) StartScheduler() {
 	}
 }
 
+// Stop 停止调度器并将当前各队列的空调状态保存到数据库
+func (s *ACScheduler) Stop() {
+	s.mu.Lock()
+	running := s.isRunning
+	s.stopped = true
+	s.mu.Unlock()
+
+	// 等待调度循环完成当前tick并退出
+	if running {
+		s.stopChan <- true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.persist {
+		s.saveACStatesToDB()
+	}
+	log.Printf("调度器状态已保存 - 服务队列: %d, 缓冲队列: %d, 回温队列: %d",
+		len(s.servingQueue), len(s.bufferQueue), len(s.warmingQueue))
+}
+
+// Step 同步执行n个tick并返回执行后的tick计数
+// 使用虚拟时钟时，每个tick前会将时钟推进一个tick间隔
+func (s *ACScheduler) Step(n int) int {
+	for i := 0; i < n; i++ {
+		if vc, ok := s.clock.(*VirtualClock); ok {
+			vc.Advance(s.Config().TickInterval)
+		}
+		s.scheduleAirConditioners()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tickCount
+}
+
+// SchedulerSnapshot 调度器某一时刻的状态快照，队列中的元素为副本
+type SchedulerSnapshot struct {
+	TickCount       int                `json:"tick_count"`
+	CurrentPriority int                `json:"current_priority"`
+	Policy          string             `json:"policy"`
+	ServingQueue    []models.Scheduler `json:"serving_queue"`
+	BufferQueue     []models.Scheduler `json:"buffer_queue"`
+	WarmingQueue    []models.Scheduler `json:"warming_queue"`
+}
+
+// Snapshot 获取调度器当前状态快照
+func (s *ACScheduler) Snapshot() SchedulerSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.snapshot()
+}
+
+// snapshot 生成调度器状态快照，调用方需持有锁
+func (s *ACScheduler) snapshot() SchedulerSnapshot {
+	return SchedulerSnapshot{
+		TickCount:       s.tickCount,
+		CurrentPriority: s.currentPriority,
+		Policy:          s.policy.Name(),
+		ServingQueue:    copySchedulers(s.servingQueue),
+		BufferQueue:     copySchedulers(s.bufferQueue),
+		WarmingQueue:    copySchedulers(s.warmingQueue),
+	}
+}
+
+// copySchedulers 复制队列中的调度对象
+func copySchedulers(queue []*models.Scheduler) []models.Scheduler {
+	result := make([]models.Scheduler, 0, len(queue))
+	for _, scheduler := range queue {
+		result = append(result, *scheduler)
+	}
+	return result
+}
+
 // scheduleAirConditioners 执行刷新操作
 func (s *ACScheduler) scheduleAirConditioners() {
 	s.mu.Lock()
@@ -202,14 +424,16 @@ func (s *ACScheduler) scheduleAirConditioners() {
 	// 刷新回温队列
 	s.refreshWarmingQueue()
 
-	// 检查是否需要进行排序（每10个tick的第9个tick，即10*n-1）
-	if s.tickCount%10 == 9 {
+	// 检查是否需要进行排序（每个重排周期的最后一个tick，默认即10*n-1）
+	if s.tickCount%s.config.SortInterval == s.config.SortInterval-1 {
 
 		log.Printf("第%d个tick，开始对缓冲队列进行排序", s.tickCount+1)
+		previous := copySchedulers(s.servingQueue)
 		s.UpdateBufferQueue()
 		s.updateWarmingQueue()
-		s.sortBufferQueue()
+		s.policy.Order(s)
 		s.updateServingQueue()
+		s.emitServingChanges(previous)
 	}
 
 	// 记录当前状态
@@ -217,8 +441,12 @@ func (s *ACScheduler) scheduleAirConditioners() {
 		s.tickCount, len(s.servingQueue), len(s.bufferQueue), len(s.warmingQueue), len(s.schedulers))
 
 	// 在刷新操作结束后保存空调状态到数据库
-	s.saveACStatesToDB()
+	if s.persist {
+		s.saveACStatesToDB()
+	}
 
+	// 发布tick结束事件，供实时推送等订阅者使用
+	s.publishTick()
 }
 
 // UpdateBufferQueue 将服务队列中的变化更新到缓冲队列中
@@ -236,7 +464,7 @@ func (s *ACScheduler) UpdateBufferQueue() {
 				bufferAC.ACState = servingAC.ACState
 				bufferAC.RunningTime = servingAC.RunningTime
 				bufferAC.RoundRobinCount = servingAC.RoundRobinCount
-				log.Printf("更新缓冲队列中空调ID %d: 温度=%d°C, 状态=%d, 当前费用=%d, 总费用=%d, 运行时间=%d",
+				log.Printf("更新缓冲队列中空调ID %d: 温度=%d°C, 状态=%d, 当前费用=%.2f, 总费用=%.2f, 运行时间=%d",
 					bufferAC.ACID, bufferAC.CurrentTemp, bufferAC.ACState, bufferAC.CurrentCost, bufferAC.TotalCost, bufferAC.RunningTime)
 				break
 			}
@@ -248,32 +476,21 @@ func (s *ACScheduler) UpdateBufferQueue() {
 
 // refreshTemperature 刷新所有空调的当前温度
 func (s *ACScheduler) refreshTemperature() {
+	now := s.clock.Now()
+	clear(s.tickCharges)
 	// 刷新温度只对服务队列进行刷新
 	for _, scheduler := range s.servingQueue {
-		// 计算温度变化量
-		var tempChange int
-		switch scheduler.CurrentSpeed {
-		case "high":
-			// 高风时每tick变化0.1度
-			tempChange = 1
-		case "medium":
-			// 中风时每两个tick变化0.1度
-			if s.tickCount%2 == 0 {
-				tempChange = 1
-			} else {
-				tempChange = 0
-			}
-		case "low":
-			// 低速时每三个tick变化0.1度
-			if s.tickCount%3 == 0 {
-				tempChange = 1
-			} else {
-				tempChange = 0
-			}
-		default:
-			tempChange = 0
+		// 未达到目标温度的空调在本tick内按计费规则计费
+		if scheduler.CurrentTemp != scheduler.TargetTemp {
+			cost := float32(s.tariff.Rate(scheduler.CurrentSpeed, scheduler.RoomTypeID, now))
+			scheduler.CurrentCost += cost
+			scheduler.TotalCost += cost
+			s.tickCharges[scheduler.ACID] += cost
 		}
 
+		// 由房间热模型计算温度变化量
+		tempChange := s.thermal.Serve(scheduler, s.tickCount)
+
 		// 根据制冷或制热模式调整温度变化方向
 		if tempChange > 0 {
 			if scheduler.Mode == "cooling" {
@@ -295,22 +512,20 @@ func (s *ACScheduler) refreshTemperature() {
 					}
 				}
 			}
-
-			// 空调花费的金额等于温度变化量
-			scheduler.CurrentCost += tempChange
-			scheduler.TotalCost += tempChange
-
 		}
 		// 增加运行时间
 		scheduler.CurrentRunningTime += 6
 		scheduler.RunningTime += 6 // 每个tick为6秒
 
-		log.Printf("空调ID %d: 温度 %d°C -> %d°C, 变化量 %d°C, 当前费用 %d, 总费用 %d",
+		log.Printf("空调ID %d: 温度 %d°C -> %d°C, 变化量 %d°C, 当前费用 %.2f, 总费用 %.2f",
 			scheduler.ACID, scheduler.CurrentTemp-tempChange, scheduler.CurrentTemp,
 			tempChange, scheduler.CurrentCost, scheduler.TotalCost)
 		// 检查当前温度是否等于目标温度，如果是则修改ACState为3（达到目标温度回温）
 		if scheduler.CurrentTemp == scheduler.TargetTemp {
-			scheduler.ACState = 3
+			if scheduler.ACState != 3 {
+				scheduler.ACState = 3
+				s.publish(events.ACTargetReached{AC: s.acEvent(scheduler)})
+			}
 			log.Printf("空调ID %d 已达到目标温度，状态设置为3（达到目标温度回温）", scheduler.ACID)
 		}
 	}
@@ -318,14 +533,14 @@ func (s *ACScheduler) refreshTemperature() {
 	log.Printf("完成服务队列温度刷新，队列长度: %d", len(s.servingQueue))
 }
 
-// incrementTimeSliceCount 对队列前三中每个进行时间片调度的空调的时间片数加1
+// incrementTimeSliceCount 对服务队列中每个进行时间片调度的空调的时间片数加1
 func (s *ACScheduler) incrementTimeSliceCount() {
 	if s.currentPriority == 0 {
 		return // 没有当前时间片调度优先级，不进行时间片计数
 	}
 
-	// 对服务队列前三个空调中属于当前调度优先级的空调时间片数加1
-	for i := 0; i < len(s.servingQueue) && i < 3; i++ {
+	// 对服务队列中属于当前调度优先级的空调时间片数加1
+	for i := 0; i < len(s.servingQueue) && i < s.config.MaxServing; i++ {
 		if s.servingQueue[i].Priority == s.currentPriority {
 			s.servingQueue[i].RoundRobinCount++
 			log.Printf("空调ID %d 时间片数增加到: %d", s.servingQueue[i].ACID, s.servingQueue[i].RoundRobinCount)
@@ -333,19 +548,19 @@ func (s *ACScheduler) incrementTimeSliceCount() {
 	}
 }
 
-// checkAndSwapTimeSliceACs 检查队列前三中是否有时间片数为2的，如果有则进行队列交换
+// checkAndSwapTimeSliceACs 检查服务队列中是否有时间片用完的空调，如果有则进行队列交换
 func (s *ACScheduler) checkAndSwapTimeSliceACs() {
 	if s.currentPriority == 0 || len(s.bufferQueue) == 0 {
 		return // 没有当前时间片调度优先级或缓冲队列为空，不进行交换
 	}
 
-	// 检查服务队列前三个空调中是否有时间片数为2的
-	for i := 0; i < len(s.servingQueue) && i < 3; i++ {
-		if s.servingQueue[i].Priority == s.currentPriority && s.servingQueue[i].RoundRobinCount == 2 {
+	// 检查服务队列中是否有时间片数达到阈值的空调
+	for i := 0; i < len(s.servingQueue) && i < s.config.MaxServing; i++ {
+		if s.servingQueue[i].Priority == s.currentPriority && s.servingQueue[i].RoundRobinCount == s.config.TimeSlice {
 			// 找到缓冲队列中当前优先级的末尾位置
 			lastSamePriorityIndex := s.findLastSamePriorityIndex(s.currentPriority)
 			if lastSamePriorityIndex != -1 {
-				// 将时间片数为2的空调交换至缓冲队列当前优先级末尾
+				// 将时间片用完的空调交换至缓冲队列当前优先级末尾
 				swapAC := s.servingQueue[i]
 				replaceAC := s.bufferQueue[lastSamePriorityIndex]
 
@@ -353,18 +568,26 @@ func (s *ACScheduler) checkAndSwapTimeSliceACs() {
 				s.servingQueue[i] = replaceAC
 				s.bufferQueue[lastSamePriorityIndex] = swapAC
 
-				// 将新进入服务队列第三位置的空调时间片数改为0
-				if i == 2 {
+				// 将新进入服务队列最后位置的空调时间片数改为0
+				if i == s.config.MaxServing-1 {
 					s.servingQueue[i].RoundRobinCount = 0
 				}
 
-				log.Printf("交换空调: 服务队列位置%d的空调ID %d (时间片数2) 与缓冲队列位置%d的空调ID %d",
-					i, swapAC.ACID, lastSamePriorityIndex, replaceAC.ACID)
+				log.Printf("交换空调: 服务队列位置%d的空调ID %d (时间片数%d) 与缓冲队列位置%d的空调ID %d",
+					i, swapAC.ACID, s.config.TimeSlice, lastSamePriorityIndex, replaceAC.ACID)
 			}
 		}
 	}
 }
 
+// resetTimeSlice 清空当前时间片调度优先级和缓冲队列所有空调的时间片数
+func (s *ACScheduler) resetTimeSlice() {
+	s.currentPriority = 0
+	for _, scheduler := range s.bufferQueue {
+		scheduler.RoundRobinCount = 0
+	}
+}
+
 // findLastSamePriorityIndex 找到缓冲队列中指定优先级的最后一个位置
 func (s *ACScheduler) findLastSamePriorityIndex(priority int) int {
 	lastIndex := -1
@@ -385,29 +608,32 @@ func (s *ACScheduler) sortBufferQueue() {
 		return s.bufferQueue[i].Priority < s.bufferQueue[j].Priority
 	})
 
-	// 如果当前缓冲队列小于等于3，结束排序，清空当前时间片调度优先级，清空队列所有空调时间片数
-	if len(s.bufferQueue) <= 3 {
+	// 服务队列容量，即缓冲队列中前n个会进入服务队列
+	n := s.config.MaxServing
+
+	// 如果当前缓冲队列不超过服务容量，结束排序，清空当前时间片调度优先级，清空队列所有空调时间片数
+	if len(s.bufferQueue) <= n {
 		s.currentPriority = 0
 		for _, scheduler := range s.bufferQueue {
 			scheduler.RoundRobinCount = 0
 		}
-		log.Printf("缓冲队列长度<=3，清空时间片调度优先级和时间片数")
+		log.Printf("缓冲队列长度<=%d，清空时间片调度优先级和时间片数", n)
 		return
 	}
 
-	// 如果当前队列排第三的空调优先级高于排第四的空调优先级，结束排序
-	if s.bufferQueue[2].Priority < s.bufferQueue[3].Priority {
+	// 如果当前队列排第n的空调优先级高于排第n+1的空调优先级，结束排序
+	if s.bufferQueue[n-1].Priority < s.bufferQueue[n].Priority {
 		s.currentPriority = 0
 		for _, scheduler := range s.bufferQueue {
 			scheduler.RoundRobinCount = 0
 		}
-		log.Printf("第三位优先级高于第四位，清空时间片调度优先级和时间片数")
+		log.Printf("第%d位优先级高于第%d位，清空时间片调度优先级和时间片数", n, n+1)
 		return
 	}
 
-	// 如果当前队列排第三的空调优先级等于排第四的空调优先级
-	if s.bufferQueue[2].Priority == s.bufferQueue[3].Priority && s.bufferQueue[2].Priority != s.currentPriority {
-		thirdPriority := s.bufferQueue[2].Priority
+	// 如果当前队列排第n的空调优先级等于排第n+1的空调优先级
+	if s.bufferQueue[n-1].Priority == s.bufferQueue[n].Priority && s.bufferQueue[n-1].Priority != s.currentPriority {
+		thirdPriority := s.bufferQueue[n-1].Priority
 
 		// 如果当前时间片调度优先级为空，记录该优先级为当前时间片调度优先级
 		if s.currentPriority == 0 {
@@ -427,23 +653,23 @@ func (s *ACScheduler) sortBufferQueue() {
 		// 对该优先级的所有空调根据当前服务时间进行排序
 		s.sortByServiceTimeAndID(thirdPriority)
 
-		// 对重新排序后不在队列前三的空调，将其时间片数设置为2，在前三的将其时间片设置为0
+		// 对重新排序后不在队列前n的空调，将其时间片数设置为阈值，在前n的将其时间片设置为0
 		for i, scheduler := range s.bufferQueue {
 			if scheduler.Priority == thirdPriority {
-				if i < 3 {
+				if i < n {
 					scheduler.RoundRobinCount = 0
 				} else {
-					scheduler.RoundRobinCount = 2
+					scheduler.RoundRobinCount = s.config.TimeSlice
 				}
 			}
 		}
 		log.Printf("完成优先级%d的时间片数设置", thirdPriority)
 	}
 
-	// 在每次时间片调度开启时，对队列前三中每个进行时间片调度的空调的时间片数加1
+	// 在每次时间片调度开启时，对服务队列中每个进行时间片调度的空调的时间片数加1
 	s.incrementTimeSliceCount()
 
-	// 在完成时间片数增加后，检查队列前三中是否有时间片数为2的，如果有则进行队列交换
+	// 在完成时间片数增加后，检查服务队列中是否有时间片用完的空调，如果有则进行队列交换
 	s.checkAndSwapTimeSliceACs()
 
 }
@@ -483,7 +709,7 @@ func (s *ACScheduler) sortByServiceTimeAndID(priority int) {
 	log.Printf("完成优先级%d的服务时间和ID排序", priority)
 }
 
-// updateServingQueue 更新服务队列为缓冲队列排序后的前三个
+// updateServingQueue 更新服务队列为缓冲队列排序后的前MaxServing个
 func (s *ACScheduler) updateServingQueue() {
 	// 清空当前服务队列
 	s.servingQueue = make([]*models.Scheduler, 0)
@@ -492,13 +718,13 @@ func (s *ACScheduler) updateServingQueue() {
 		return
 	}
 
-	// 取缓冲队列前三个作为新的服务队列
-	maxServing := 3
+	// 取缓冲队列前MaxServing个作为新的服务队列
+	maxServing := s.config.MaxServing
 	if len(s.bufferQueue) < maxServing {
 		maxServing = len(s.bufferQueue)
 	}
 
-	// 将缓冲队列前三个移到服务队列
+	// 将缓冲队列前maxServing个移到服务队列
 	for i := 0; i < maxServing; i++ {
 		s.servingQueue = append(s.servingQueue, s.bufferQueue[i])
 	}
@@ -518,29 +744,31 @@ func (s *ACScheduler) updateServingQueue() {
 }
 
 func (s *ACScheduler) refreshWarmingQueue() {
-	// 回温队列中所有空调每2个tick刷新1度
+	// 回温队列中所有空调由房间热模型计算向环境温度靠拢的变化量
 	for _, scheduler := range s.warmingQueue {
-		// 每2个tick变化1度
-		if s.tickCount%2 == 0 {
-			// 根据模式决定回温方向
-			if scheduler.Mode == "cool" {
-				// 制冷模式回温：温度上升，但不能超过环境温度
-				if scheduler.CurrentTemp < scheduler.EnvironmentTemp {
-					scheduler.CurrentTemp += 1
-					log.Printf("空调ID %d 制冷回温：温度 %d°C -> %d°C", scheduler.ACID, scheduler.CurrentTemp-1, scheduler.CurrentTemp)
-				} else {
-					log.Printf("空调ID %d 制冷回温：已达到环境温度 %d°C，停止回温", scheduler.ACID, scheduler.EnvironmentTemp)
-				}
-			} else if scheduler.Mode == "heat" {
-				// 制热模式回温：温度下降，但不能低于环境温度
-				if scheduler.CurrentTemp > scheduler.EnvironmentTemp {
-					scheduler.CurrentTemp -= 1
-					log.Printf("空调ID %d 制热回温：温度 %d°C -> %d°C", scheduler.ACID, scheduler.CurrentTemp+1, scheduler.CurrentTemp)
-				} else {
-					log.Printf("空调ID %d 制热回温：已达到环境温度 %d°C，停止回温", scheduler.ACID, scheduler.EnvironmentTemp)
-				}
+		tempChange := s.thermal.Drift(scheduler, s.tickCount)
+		if tempChange <= 0 {
+			continue
+		}
+
+		before := scheduler.CurrentTemp
+		if scheduler.CurrentTemp < scheduler.EnvironmentTemp {
+			// 低于环境温度：温度上升，但不能超过环境温度
+			scheduler.CurrentTemp += tempChange
+			if scheduler.CurrentTemp > scheduler.EnvironmentTemp {
+				scheduler.CurrentTemp = scheduler.EnvironmentTemp
+			}
+		} else if scheduler.CurrentTemp > scheduler.EnvironmentTemp {
+			// 高于环境温度：温度下降，但不能低于环境温度
+			scheduler.CurrentTemp -= tempChange
+			if scheduler.CurrentTemp < scheduler.EnvironmentTemp {
+				scheduler.CurrentTemp = scheduler.EnvironmentTemp
 			}
 		}
+
+		if scheduler.CurrentTemp != before {
+			log.Printf("空调ID %d 回温：温度 %d°C -> %d°C", scheduler.ACID, before, scheduler.CurrentTemp)
+		}
 	}
 
 	if len(s.warmingQueue) > 0 {
@@ -555,12 +783,13 @@ func (s *ACScheduler) updateWarmingQueue() {
 	for _, scheduler := range s.bufferQueue {
 		if scheduler.ACState == 2 || scheduler.ACState == 3 {
 			// 当ACState为2时，需要额外操作：在空调操作表中查找当前账单号最后一次关机调度并保存信息
-			if scheduler.ACState == 2 {
+			if scheduler.ACState == 2 && s.persist {
 				s.saveShutdownOperationToDB(scheduler)
 			}
 
 			// 移除并加入回温队列
 			s.warmingQueue = append(s.warmingQueue, scheduler)
+			s.publish(events.ACWarming{AC: s.acEvent(scheduler)})
 			log.Printf("空调ID %d 从缓冲队列移除并加入回温队列，ACState: %d", scheduler.ACID, scheduler.ACState)
 		} else {
 			// 保留在缓冲队列中
@@ -569,7 +798,7 @@ func (s *ACScheduler) updateWarmingQueue() {
 	}
 	s.bufferQueue = newBufferQueue
 
-	// 第二步：检查回温队列中ACState为3且当前温度和目标温度差值绝对值为10的
+	// 第二步：检查回温队列中ACState为3且当前温度和目标温度差值绝对值达到回温温差的
 	var newWarmingQueue []*models.Scheduler
 	for _, scheduler := range s.warmingQueue {
 		if scheduler.ACState == 3 {
@@ -579,10 +808,11 @@ func (s *ACScheduler) updateWarmingQueue() {
 				tempDiff = -tempDiff
 			}
 
-			if tempDiff == 10 {
+			if tempDiff >= s.config.RewarmDelta {
 				// 修改ACState为1，移出回温队列，加入缓冲队列
 				scheduler.ACState = 1
 				s.bufferQueue = append(s.bufferQueue, scheduler)
+				s.publish(events.ACRequeued{AC: s.acEvent(scheduler)})
 				log.Printf("空调ID %d 从回温队列移除并加入缓冲队列，ACState设置为1（在等待序列），温度差值: %d", scheduler.ACID, tempDiff)
 			} else {
 				// 保留在回温队列中
@@ -598,7 +828,7 @@ func (s *ACScheduler) updateWarmingQueue() {
 	log.Printf("updateWarmingQueue完成 - 缓冲队列: %d, 回温队列: %d", len(s.bufferQueue), len(s.warmingQueue))
 }
 
-// GetSchedulerStatus 获取调度器状态（管理员接口）
+// GetSchedulerStatus 获取调度器状态（需要 scheduler:read 权限）
 func GetSchedulerStatus(c *gin.Context) {
 	scheduler := GetScheduler()
 	scheduler.mu.RLock()
@@ -613,6 +843,7 @@ func GetSchedulerStatus(c *gin.Context) {
 		"total_requests":   len(scheduler.schedulers),
 		"tick_count":       scheduler.tickCount,
 		"current_priority": scheduler.currentPriority,
+		"policy":           scheduler.policy.Name(),
 		"first_ac_added":   scheduler.firstACAdded,
 		"serving_queue":    scheduler.servingQueue,
 		"buffer_queue":     scheduler.bufferQueue,
@@ -620,53 +851,64 @@ func GetSchedulerStatus(c *gin.Context) {
 	})
 }
 
-// saveACStatesToDB 保存空调状态到数据库
-// 按照指定顺序：先保存服务队列中的内容，再保存缓存队列第四个开始的内容，最后保存回温队列中的内容
-func (s *ACScheduler) saveACStatesToDB() {
-	// 1. 先保存服务队列中的内容
+// acQueueStatus 空调及其对外展示的状态
+type acQueueStatus struct {
+	ac     *models.Scheduler
+	status int // 0-运行 1-在等待序列 2-关机回温 3-达到目标温度回温
+}
+
+// queueStatuses 按照指定顺序获取各队列中空调的状态：
+// 先是服务队列中的内容，再是缓存队列中未进入服务队列的内容，最后是回温队列中的内容
+func (s *ACScheduler) queueStatuses() []acQueueStatus {
+	statuses := make([]acQueueStatus, 0, len(s.servingQueue)+len(s.bufferQueue)+len(s.warmingQueue))
+
+	// 1. 服务队列中的内容
 	for _, ac := range s.servingQueue {
-		s.saveACDetailToDB(ac, 0) // ACStatus = 0 表示运行状态
+		statuses = append(statuses, acQueueStatus{ac: ac, status: 0}) // ACStatus = 0 表示运行状态
 	}
 
-	// 2. 再保存缓存队列第四个开始的内容
-	for i := 3; i < len(s.bufferQueue); i++ { // 从第4个开始（索引3）
-		s.saveACDetailToDB(s.bufferQueue[i], 1) // ACStatus = 1 表示在等待序列
+	// 2. 缓存队列中未进入服务队列的内容
+	for i := s.config.MaxServing; i < len(s.bufferQueue); i++ { // 从第MaxServing+1个开始
+		statuses = append(statuses, acQueueStatus{ac: s.bufferQueue[i], status: 1}) // ACStatus = 1 表示在等待序列
 	}
 
-	// 3. 最后保存回温队列中的内容
+	// 3. 回温队列中的内容
 	for _, ac := range s.warmingQueue {
 		// 根据ACState判断回温状态：2-关机回温，3-达到目标温度回温
 		acStatus := 2
 		if ac.ACState == 3 {
 			acStatus = 3
 		}
-		s.saveACDetailToDB(ac, acStatus)
+		statuses = append(statuses, acQueueStatus{ac: ac, status: acStatus})
 	}
+	return statuses
+}
 
-	log.Printf("已保存空调状态到数据库 - 服务队列: %d, 缓冲队列(第4个开始): %d, 回温队列: %d",
+// saveACStatesToDB 保存空调状态到数据库
+// 按照指定顺序：先保存服务队列中的内容，再保存缓存队列中未进入服务队列的内容，最后保存回温队列中的内容
+func (s *ACScheduler) saveACStatesToDB() {
+	for _, entry := range s.queueStatuses() {
+		s.saveACDetailToDB(entry.ac, entry.status)
+	}
+
+	log.Printf("已保存空调状态到数据库 - 服务队列: %d, 缓冲队列(等待中): %d, 回温队列: %d",
 		len(s.servingQueue),
-		max(0, len(s.bufferQueue)-3),
+		max(0, len(s.bufferQueue)-s.config.MaxServing),
 		len(s.warmingQueue))
 }
 
 // saveACDetailToDB 保存单个空调状态到数据库
 func (s *ACScheduler) saveACDetailToDB(ac *models.Scheduler, acStatus int) {
-	// 计算费率（根据风速）
-	var rate float32
-	switch ac.CurrentSpeed {
-	case "high":
-		rate = 1.0
-	case "medium":
-		rate = 0.5
-	case "low":
-		rate = 0.33
-	default:
-		rate = 0.5
-	}
+	// 计算当前费率（与调度器计费使用同一套规则）
+	rate := float32(s.tariff.Rate(ac.CurrentSpeed, ac.RoomTypeID, s.clock.Now()))
 
 	// 计算温度变化（当前温度与环境温度的差值）
 	tempChange := ac.CurrentTemp - ac.EnvironmentTemp
 
+	// 本tick的计费金额只记录一次，账单空调费用为这些金额的合计
+	charge := s.tickCharges[ac.ACID]
+	delete(s.tickCharges, ac.ACID)
+
 	// 创建空调状态记录
 	acDetail := models.AirConditionerDetail{
 		BillID:             ac.BillID,
@@ -680,9 +922,10 @@ func (s *ACScheduler) saveACDetailToDB(ac *models.Scheduler, acStatus int) {
 		CurrentTemp:        ac.CurrentTemp,
 		RunningTime:        ac.RunningTime,
 		CurrentRunningTime: ac.CurrentRunningTime,
-		CurrentCost:        float32(ac.CurrentCost),
-		TotalCost:          float32(ac.TotalCost),
+		CurrentCost:        ac.CurrentCost,
+		TotalCost:          ac.TotalCost,
 		Rate:               rate,
+		Charge:             charge,
 		TempChange:         tempChange,
 	}
 
@@ -707,11 +950,11 @@ func (s *ACScheduler) saveShutdownOperationToDB(scheduler *models.Scheduler) {
 	}
 
 	// 更新最后一次关机调度记录，保存当前花费、当前温度、当前时间
-	lastShutdownOp.CurrentCost = float32(scheduler.CurrentCost)
+	lastShutdownOp.CurrentCost = scheduler.CurrentCost
 	lastShutdownOp.CurrentTemp = scheduler.CurrentTemp
 	lastShutdownOp.RunningTime = scheduler.RunningTime // 使用RunningTime作为当前时间
 	lastShutdownOp.CurrentRunningTime = scheduler.CurrentRunningTime
-	lastShutdownOp.UpdatedAt = time.Now()
+	lastShutdownOp.UpdatedAt = s.clock.Now()
 
 	// 保存更新到数据库
 	if err := database.DB.Save(&lastShutdownOp).Error; err != nil {
@@ -719,7 +962,7 @@ func (s *ACScheduler) saveShutdownOperationToDB(scheduler *models.Scheduler) {
 			scheduler.BillID, scheduler.RoomID, err)
 	} else {
 		log.Printf("保存关机调度信息成功 - BillID: %d, RoomID: %d, CurrentCost: %.2f, CurrentTemp: %d, RunningTime: %d",
-			scheduler.BillID, scheduler.RoomID, float32(scheduler.CurrentCost), scheduler.CurrentTemp, scheduler.RunningTime)
+			scheduler.BillID, scheduler.RoomID, scheduler.CurrentCost, scheduler.CurrentTemp, scheduler.RunningTime)
 	}
 }
 
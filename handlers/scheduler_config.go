@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+)
+
+// SchedulerConfig 调度器参数，用于模拟不同规模的中央空调机组
+type SchedulerConfig struct {
+	MaxServing   int           `yaml:"max_serving"`   // 同时服务的空调数量
+	SortInterval int           `yaml:"sort_interval"` // 缓冲队列重排周期（tick），在每个周期的最后一个tick执行
+	TimeSlice    int           `yaml:"time_slice"`    // 时间片轮转阈值，同优先级空调服务满该数量的调度周期后让出服务
+	RewarmDelta  int           `yaml:"rewarm_delta"`  // 达到目标温度后回温到与目标温度相差该值（*10）时重新请求服务
+	TickInterval time.Duration `yaml:"tick_interval"` // tick间隔
+}
+
+// DefaultSchedulerConfig 返回默认调度器参数
+func DefaultSchedulerConfig() SchedulerConfig {
+	return SchedulerConfig{
+		MaxServing:   3,
+		SortInterval: 10,
+		TimeSlice:    2,
+		RewarmDelta:  10,
+		TickInterval: DefaultTickInterval,
+	}
+}
+
+// withDefaults 使用默认值补全未设置的参数
+func (c SchedulerConfig) withDefaults() SchedulerConfig {
+	defaults := DefaultSchedulerConfig()
+	if c.MaxServing == 0 {
+		c.MaxServing = defaults.MaxServing
+	}
+	if c.SortInterval == 0 {
+		c.SortInterval = defaults.SortInterval
+	}
+	if c.TimeSlice == 0 {
+		c.TimeSlice = defaults.TimeSlice
+	}
+	if c.RewarmDelta == 0 {
+		c.RewarmDelta = defaults.RewarmDelta
+	}
+	if c.TickInterval == 0 {
+		c.TickInterval = defaults.TickInterval
+	}
+	return c
+}
+
+// Validate 校验调度器参数
+func (c SchedulerConfig) Validate() error {
+	if c.MaxServing < 1 {
+		return fmt.Errorf("同时服务的空调数量必须大于0: %d", c.MaxServing)
+	}
+	if c.SortInterval < 1 {
+		return fmt.Errorf("缓冲队列重排周期必须大于0: %d", c.SortInterval)
+	}
+	if c.TimeSlice < 1 {
+		return fmt.Errorf("时间片轮转阈值必须大于0: %d", c.TimeSlice)
+	}
+	if c.RewarmDelta < 1 {
+		return fmt.Errorf("回温重新请求温差必须大于0: %d", c.RewarmDelta)
+	}
+	if c.TickInterval <= 0 {
+		return fmt.Errorf("tick间隔必须大于0: %v", c.TickInterval)
+	}
+	return nil
+}
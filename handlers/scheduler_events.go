@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"bupt-hotel/events"
+	"bupt-hotel/models"
+)
+
+// EventSchedulerTick 调度器tick结束事件名称
+const EventSchedulerTick = "scheduler_tick"
+
+// SchedulerTick 调度器tick结束事件，携带该tick结束时的调度器快照和各房间的空调状态
+type SchedulerTick struct {
+	Snapshot SchedulerSnapshot               `json:"snapshot"`
+	Statuses map[StatusKey]*ACStatusResponse `json:"-"`
+	Time     time.Time                       `json:"time"`
+}
+
+func (SchedulerTick) EventName() string { return EventSchedulerTick }
+
+var (
+	eventBusInstance *events.Bus
+	eventBusOnce     sync.Once
+)
+
+// GetEventBus 获取全局事件总线单例，全局调度器的事件发布到这里
+func GetEventBus() *events.Bus {
+	eventBusOnce.Do(func() {
+		eventBusInstance = events.New()
+	})
+	return eventBusInstance
+}
+
+// publish 发布调度事件，未设置事件总线时不发布
+func (s *ACScheduler) publish(event events.Event) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(event)
+}
+
+// acEvent 生成空调调度事件的公共字段
+func (s *ACScheduler) acEvent(ac *models.Scheduler) events.AC {
+	return events.AC{
+		Tick:    s.tickCount,
+		ACID:    ac.ACID,
+		RoomID:  ac.RoomID,
+		BillID:  ac.BillID,
+		ACState: ac.ACState,
+		Time:    s.clock.Now(),
+	}
+}
+
+// emitServingChanges 对比重排前后的服务队列，产生进入服务和被抢占事件
+// 离开服务队列后仍在等待序列中的视为被抢占，移入回温队列的由updateWarmingQueue产生事件
+func (s *ACScheduler) emitServingChanges(previous []models.Scheduler) {
+	if s.events == nil {
+		return
+	}
+
+	wasServing := make(map[int]bool, len(previous))
+	for _, ac := range previous {
+		wasServing[ac.ACID] = true
+	}
+	isServing := make(map[int]bool, len(s.servingQueue))
+	for _, ac := range s.servingQueue {
+		isServing[ac.ACID] = true
+		if !wasServing[ac.ACID] {
+			s.publish(events.ACAdmitted{AC: s.acEvent(ac)})
+		}
+	}
+	for _, ac := range s.bufferQueue {
+		if wasServing[ac.ACID] && !isServing[ac.ACID] {
+			s.publish(events.ACPreempted{AC: s.acEvent(ac)})
+		}
+	}
+}
+
+// publishTick 发布tick结束事件
+func (s *ACScheduler) publishTick() {
+	if s.events == nil {
+		return
+	}
+
+	statuses := make(map[StatusKey]*ACStatusResponse)
+	for _, entry := range s.queueStatuses() {
+		key := StatusKey{RoomID: entry.ac.RoomID, BillID: entry.ac.BillID}
+		statuses[key] = schedulerToStatusResponse(entry.ac, entry.status)
+	}
+
+	s.publish(SchedulerTick{
+		Snapshot: s.snapshot(),
+		Statuses: statuses,
+		Time:     s.clock.Now(),
+	})
+}
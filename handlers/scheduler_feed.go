@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+
+	"bupt-hotel/events"
+)
+
+// feedBufferSize 每个订阅者最多缓存的未发送tick数，超过时丢弃新的tick
+const feedBufferSize = 16
+
+// FeedTick 推送给监控面板的一个tick：tick结束时的调度器快照及本tick内发生的调度事件
+type FeedTick struct {
+	Snapshot SchedulerSnapshot `json:"snapshot"`
+	Events   []events.Event    `json:"events"`
+}
+
+// SchedulerFeed 调度器状态推送中心，供管理员监控面板实时订阅
+type SchedulerFeed struct {
+	mu          sync.Mutex
+	closed      bool
+	subscribers map[chan FeedTick]struct{}
+
+	pending []events.Event // 本tick内收到、尚未推送的调度事件，只在事件总线的处理协程中访问
+}
+
+var (
+	schedulerFeedInstance *SchedulerFeed
+	schedulerFeedOnce     sync.Once
+)
+
+// GetSchedulerFeed 获取调度器状态推送中心单例，订阅全局事件总线
+func GetSchedulerFeed() *SchedulerFeed {
+	schedulerFeedOnce.Do(func() {
+		schedulerFeedInstance = NewSchedulerFeed()
+		schedulerFeedInstance.Listen(GetEventBus())
+	})
+	return schedulerFeedInstance
+}
+
+// NewSchedulerFeed 创建调度器状态推送中心
+func NewSchedulerFeed() *SchedulerFeed {
+	return &SchedulerFeed{
+		subscribers: make(map[chan FeedTick]struct{}),
+	}
+}
+
+// Subscribe 订阅调度器每个tick的快照和事件，返回通道和取消订阅函数；推送中心关闭后通道被关闭
+func (f *SchedulerFeed) Subscribe() (<-chan FeedTick, func()) {
+	ch := make(chan FeedTick, feedBufferSize)
+
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	f.subscribers[ch] = struct{}{}
+	f.mu.Unlock()
+
+	cancel := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if _, ok := f.subscribers[ch]; !ok {
+			return // 已取消或推送中心已关闭
+		}
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+	return ch, cancel
+}
+
+// Listen 订阅事件总线：调度事件先缓存，收到tick结束事件时与快照一起推送
+func (f *SchedulerFeed) Listen(bus *events.Bus) func() {
+	return bus.Subscribe("scheduler_feed", func(event events.Event) {
+		tick, ok := event.(SchedulerTick)
+		if !ok {
+			f.pending = append(f.pending, event)
+			return
+		}
+		f.Publish(FeedTick{Snapshot: tick.Snapshot, Events: f.pending})
+		f.pending = nil
+	})
+}
+
+// Publish 向所有订阅者推送一个tick，订阅者缓存已满时丢弃该tick
+func (f *SchedulerFeed) Publish(tick FeedTick) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- tick:
+		default:
+			log.Printf("调度器状态订阅者处理过慢，丢弃第%d个tick", tick.Snapshot.TickCount)
+		}
+	}
+}
+
+// Close 关闭推送中心，关闭所有订阅通道（服务关闭时调用）
+func (f *SchedulerFeed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return
+	}
+	f.closed = true
+	for ch := range f.subscribers {
+		close(ch)
+		delete(f.subscribers, ch)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"log"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+// RecoverState 根据已入住房间最新的空调操作和状态记录重建调度队列
+// 最后一次开关机操作为开机的空调会被恢复：达到目标温度回温的进入回温队列，其余进入缓冲队列，
+// 然后按当前调度策略排序并重新生成服务队列。返回恢复的空调数量
+func (s *ACScheduler) RecoverState() (int, error) {
+	var rooms []models.RoomInfo
+	if err := database.DB.Where("state = ?", models.RoomOccupied).Find(&rooms).Error; err != nil {
+		return 0, err
+	}
+
+	var recovered []*models.Scheduler
+	for _, room := range rooms {
+		scheduler, ok := loadSchedulerFromDB(room)
+		if ok {
+			recovered = append(recovered, scheduler)
+		}
+	}
+
+	if len(recovered) == 0 {
+		log.Println("没有需要恢复的空调调度状态")
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, scheduler := range recovered {
+		s.schedulers[scheduler.ACID] = scheduler
+		if scheduler.ACState == 3 {
+			s.warmingQueue = append(s.warmingQueue, scheduler)
+		} else {
+			scheduler.ACState = 1
+			s.bufferQueue = append(s.bufferQueue, scheduler)
+		}
+	}
+
+	s.policy.Order(s)
+	s.updateServingQueue()
+	s.firstACAdded = true
+
+	if !s.isRunning && !s.manual {
+		go s.StartScheduler()
+	}
+
+	log.Printf("已恢复空调调度状态 - 服务队列: %d, 缓冲队列: %d, 回温队列: %d",
+		len(s.servingQueue), len(s.bufferQueue), len(s.warmingQueue))
+	return len(recovered), nil
+}
+
+// loadSchedulerFromDB 从数据库中加载房间当前账单下仍处于开机状态的空调
+func loadSchedulerFromDB(room models.RoomInfo) (*models.Scheduler, bool) {
+	roomID := room.RoomID
+
+	// 获取当前入住的账单号
+	billID, err := currentBillID(database.DB, roomID)
+	if err != nil {
+		return nil, false
+	}
+
+	// 最后一次开关机操作为关机或没有操作时不需要恢复
+	lastOp, on := activeACSettings(roomID, billID)
+	if !on {
+		return nil, false
+	}
+
+	var ac models.AirConditioner
+	if err := database.DB.Where("room_id = ?", roomID).First(&ac).Error; err != nil {
+		log.Printf("恢复调度状态时未找到房间 %d 的空调: %v", roomID, err)
+		return nil, false
+	}
+
+	scheduler := &models.Scheduler{
+		ACID:            ac.ID,
+		BillID:          billID,
+		RoomID:          roomID,
+		RoomTypeID:      room.RoomTypeID,
+		ACState:         1,
+		Mode:            lastOp.Mode,
+		Priority:        PriorityForSpeed(lastOp.Speed),
+		CurrentSpeed:    lastOp.Speed,
+		CurrentTemp:     ac.EnvironmentTemp,
+		TargetTemp:      lastOp.TargetTemp,
+		EnvironmentTemp: ac.EnvironmentTemp,
+	}
+
+	// 使用最新的空调状态记录恢复温度、费用和运行时间
+	var detail models.AirConditionerDetail
+	if err := database.DB.Where("room_id = ? AND bill_id = ?", roomID, billID).Order("created_at DESC").First(&detail).Error; err == nil {
+		scheduler.CurrentTemp = detail.CurrentTemp
+		scheduler.CurrentCost = detail.CurrentCost
+		scheduler.TotalCost = detail.TotalCost
+		scheduler.RunningTime = detail.RunningTime
+		scheduler.CurrentRunningTime = detail.CurrentRunningTime
+		if detail.ACStatus == 3 {
+			scheduler.ACState = 3
+		}
+	}
+
+	log.Printf("恢复空调ID %d（房间 %d，账单 %d）: 温度=%d, 目标温度=%d, 风速=%s, 状态=%d",
+		scheduler.ACID, roomID, billID, scheduler.CurrentTemp, scheduler.TargetTemp, scheduler.CurrentSpeed, scheduler.ACState)
+	return scheduler, true
+}
+
+// activeACSettings 判断房间当前账单下的空调是否开机，开机时返回最新一次操作记录中的模式、目标温度和风速
+// 开关状态只由最后一次开机或关机操作决定，关机后的调温操作不会使空调重新开机
+func activeACSettings(roomID, billID int) (models.AirConditionerOperation, bool) {
+	var power models.AirConditionerOperation
+	if err := database.DB.Where("room_id = ? AND bill_id = ? AND operation_state IN (0, 1)", roomID, billID).Order("created_at DESC").First(&power).Error; err != nil {
+		return models.AirConditionerOperation{}, false
+	}
+	if power.OperationState == 1 {
+		return models.AirConditionerOperation{}, false
+	}
+
+	var lastOp models.AirConditionerOperation
+	if err := database.DB.Where("room_id = ? AND bill_id = ?", roomID, billID).Order("created_at DESC").First(&lastOp).Error; err != nil {
+		return models.AirConditionerOperation{}, false
+	}
+	return lastOp, true
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+// setupTestDB 在临时目录中初始化SQLite数据库，测试结束时关闭连接
+func setupTestDB(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "hotel.db")
+	if err := database.InitDatabase(path); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		database.CloseDatabase()
+	})
+	return path
+}
+
+// checkinTestRoom 直接写入入住记录，使房间处于已入住状态，返回账单号
+func checkinTestRoom(t *testing.T, roomID int) int {
+	t.Helper()
+
+	now := time.Now()
+	billID := int(now.Unix())*1000 + roomID
+	if err := database.DB.Model(&models.RoomInfo{}).Where("room_id = ?", roomID).Updates(map[string]interface{}{
+		"state":         models.RoomOccupied,
+		"client_id":     "1",
+		"client_name":   "test",
+		"checkin_time":  now,
+		"checkout_time": now.AddDate(0, 0, 1),
+	}).Error; err != nil {
+		t.Fatalf("更新房间状态失败: %v", err)
+	}
+	if err := database.DB.Create(&models.RoomOperation{
+		RoomID:        roomID,
+		BillID:        billID,
+		ClientID:      "1",
+		ClientName:    "test",
+		OperationType: "checkin",
+		OperationTime: now,
+		CheckinTime:   now,
+		CheckoutTime:  now.AddDate(0, 0, 1),
+	}).Error; err != nil {
+		t.Fatalf("创建入住记录失败: %v", err)
+	}
+	return billID
+}
+
+func TestRecoverStateAfterRestart(t *testing.T) {
+	path := setupTestDB(t)
+
+	const roomID = 101
+	billID := checkinTestRoom(t, roomID)
+
+	var ac models.AirConditioner
+	if err := database.DB.Where("room_id = ?", roomID).First(&ac).Error; err != nil {
+		t.Fatalf("查询空调失败: %v", err)
+	}
+
+	// 开机：与applyACOperation一致，先保存开机操作记录再向调度器提交请求
+	operation := models.AirConditionerOperation{
+		BillID:          billID,
+		RoomID:          roomID,
+		AcID:            ac.ID,
+		OperationState:  0,
+		Mode:            "heating",
+		Speed:           "high",
+		TargetTemp:      260,
+		EnvironmentTemp: ac.EnvironmentTemp,
+		CurrentTemp:     ac.EnvironmentTemp,
+	}
+	if err := database.DB.Create(&operation).Error; err != nil {
+		t.Fatalf("保存开机操作失败: %v", err)
+	}
+
+	before := NewACScheduler(SchedulerOptions{Clock: NewVirtualClock(time.Now())})
+	before.AddRequest(&models.Scheduler{
+		ACID:            ac.ID,
+		RoomID:          roomID,
+		BillID:          billID,
+		Mode:            operation.Mode,
+		Priority:        PriorityForSpeed(operation.Speed),
+		CurrentSpeed:    operation.Speed,
+		CurrentTemp:     ac.EnvironmentTemp,
+		TargetTemp:      operation.TargetTemp,
+		EnvironmentTemp: ac.EnvironmentTemp,
+	})
+	before.Step(5)
+	before.Stop()
+
+	// 重启：关闭并重新打开数据库，用新的调度器实例恢复状态
+	if err := database.CloseDatabase(); err != nil {
+		t.Fatalf("关闭数据库失败: %v", err)
+	}
+	if err := database.InitDatabase(path); err != nil {
+		t.Fatalf("重新打开数据库失败: %v", err)
+	}
+
+	var saved models.AirConditionerOperation
+	if err := database.DB.First(&saved, operation.ID).Error; err != nil {
+		t.Fatalf("查询开机操作失败: %v", err)
+	}
+	if saved.OperationState != 0 {
+		t.Fatalf("开机操作保存后的状态为 %d，期望 0", saved.OperationState)
+	}
+
+	after := NewACScheduler(SchedulerOptions{Clock: NewVirtualClock(time.Now())})
+	recovered, err := after.RecoverState()
+	if err != nil {
+		t.Fatalf("恢复调度状态失败: %v", err)
+	}
+	if recovered != 1 {
+		t.Fatalf("恢复了 %d 台空调，期望 1", recovered)
+	}
+
+	snapshot := after.Snapshot()
+	if len(snapshot.ServingQueue) != 1 || snapshot.ServingQueue[0].ACID != ac.ID {
+		t.Fatalf("服务队列为 %v，期望空调 %d", acIDs(snapshot.ServingQueue), ac.ID)
+	}
+	got := snapshot.ServingQueue[0]
+	if got.BillID != billID || got.TargetTemp != 260 || got.CurrentSpeed != "high" {
+		t.Fatalf("恢复的空调参数不正确: %+v", got)
+	}
+	// 高速每tick升温0.1度，重启前运行了5个tick
+	if want := ac.EnvironmentTemp + 5; got.CurrentTemp != want {
+		t.Fatalf("恢复的当前温度为 %d，期望 %d", got.CurrentTemp, want)
+	}
+}
+
+func TestRecoverStateIgnoresSettingsAfterShutdown(t *testing.T) {
+	setupTestDB(t)
+
+	const roomID = 101
+	billID := checkinTestRoom(t, roomID)
+
+	var ac models.AirConditioner
+	if err := database.DB.Where("room_id = ?", roomID).First(&ac).Error; err != nil {
+		t.Fatalf("查询空调失败: %v", err)
+	}
+
+	// 开机、关机后再调温：调温不会使空调重新开机，重启后不应恢复
+	now := time.Now()
+	for i, state := range []int{0, 1, 2} {
+		if err := database.DB.Create(&models.AirConditionerOperation{
+			BillID:          billID,
+			RoomID:          roomID,
+			AcID:            ac.ID,
+			OperationState:  state,
+			Mode:            "heating",
+			Speed:           "high",
+			TargetTemp:      260,
+			EnvironmentTemp: ac.EnvironmentTemp,
+			CurrentTemp:     ac.EnvironmentTemp,
+			CreatedAt:       now.Add(time.Duration(i) * time.Second),
+		}).Error; err != nil {
+			t.Fatalf("保存空调操作失败: %v", err)
+		}
+	}
+
+	after := NewACScheduler(SchedulerOptions{Clock: NewVirtualClock(time.Now())})
+	recovered, err := after.RecoverState()
+	if err != nil {
+		t.Fatalf("恢复调度状态失败: %v", err)
+	}
+	if recovered != 0 {
+		t.Fatalf("恢复了 %d 台空调，期望 0", recovered)
+	}
+}
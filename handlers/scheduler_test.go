@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"bupt-hotel/models"
+)
+
+// testAC 测试场景中的空调，所有空调均从30度开始制冷
+type testAC struct {
+	id     int
+	speed  string
+	target int
+}
+
+// queueState 某个tick结束时各队列中的空调ID
+type queueState struct {
+	serving []int
+	buffer  []int
+	warming []int
+}
+
+// newStepScheduler 创建使用虚拟时钟、不写数据库的调度器，并按顺序提交开机请求
+func newStepScheduler(t *testing.T, policyName string, config SchedulerConfig, acs []testAC) *ACScheduler {
+	t.Helper()
+
+	policy, err := NewSchedulingPolicy(policyName)
+	if err != nil {
+		t.Fatalf("创建调度策略失败: %v", err)
+	}
+
+	s := NewACScheduler(SchedulerOptions{
+		Clock:              NewVirtualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)),
+		Config:             config,
+		Policy:             policy,
+		DisablePersistence: true,
+	})
+	for _, ac := range acs {
+		s.AddRequest(&models.Scheduler{
+			ACID:            ac.id,
+			RoomID:          ac.id,
+			Mode:            "cooling",
+			Priority:        PriorityForSpeed(ac.speed),
+			CurrentSpeed:    ac.speed,
+			CurrentTemp:     300,
+			TargetTemp:      ac.target,
+			EnvironmentTemp: 300,
+		})
+	}
+	return s
+}
+
+// snapshotQueues 获取调度器当前各队列中的空调ID
+func snapshotQueues(s *ACScheduler) queueState {
+	snapshot := s.Snapshot()
+	return queueState{
+		serving: acIDs(snapshot.ServingQueue),
+		buffer:  acIDs(snapshot.BufferQueue),
+		warming: acIDs(snapshot.WarmingQueue),
+	}
+}
+
+func acIDs(queue []models.Scheduler) []int {
+	ids := make([]int, 0, len(queue))
+	for _, ac := range queue {
+		ids = append(ids, ac.ACID)
+	}
+	return ids
+}
+
+// checkQueueInvariants 检查每个空调恰好位于缓冲队列或回温队列之一，且服务队列是缓冲队列的前缀
+func checkQueueInvariants(t *testing.T, tick int, state queueState, acs []testAC, maxServing int) {
+	t.Helper()
+
+	seen := make(map[int]int)
+	for _, id := range state.buffer {
+		seen[id]++
+	}
+	for _, id := range state.warming {
+		seen[id]++
+	}
+	for _, ac := range acs {
+		if seen[ac.id] != 1 {
+			t.Fatalf("tick %d: 空调 %d 在缓冲和回温队列中出现 %d 次: %+v", tick, ac.id, seen[ac.id], state)
+		}
+	}
+
+	if len(state.serving) > maxServing {
+		t.Fatalf("tick %d: 服务队列超过容量 %d: %+v", tick, maxServing, state)
+	}
+	if len(state.serving) > len(state.buffer) || !reflect.DeepEqual(state.serving, state.buffer[:len(state.serving)]) {
+		t.Fatalf("tick %d: 服务队列不是缓冲队列的前缀: %+v", tick, state)
+	}
+}
+
+func TestSchedulerStepPolicies(t *testing.T) {
+	// 低速、中速各一台，高速两台，服务容量为2
+	acs := []testAC{
+		{id: 1, speed: "low", target: 180},
+		{id: 2, speed: "medium", target: 260},
+		{id: 3, speed: "high", target: 200},
+		{id: 4, speed: "high", target: 240},
+	}
+	config := SchedulerConfig{MaxServing: 2}
+
+	// 第一个开机的空调直接进入服务队列，之后每10个tick的最后一个tick（9, 19, ...）按策略重排
+	initial := queueState{serving: []int{1}, buffer: []int{1, 2, 3, 4}, warming: []int{}}
+
+	tests := []struct {
+		policy      string
+		checkpoints map[int]queueState
+	}{
+		{
+			// 保持开机顺序；中速的空调2在tick 89达到目标温度，回温10个tick后回到缓冲队列末尾
+			policy: PolicyFIFO,
+			checkpoints: map[int]queueState{
+				8:   initial,
+				9:   {serving: []int{1, 2}, buffer: []int{1, 2, 3, 4}, warming: []int{}},
+				89:  {serving: []int{1, 3}, buffer: []int{1, 3, 4}, warming: []int{2}},
+				109: {serving: []int{1, 3}, buffer: []int{1, 3, 4, 2}, warming: []int{}},
+			},
+		},
+		{
+			// 两台高速空调先服务；空调4在tick 69达到目标温度，回温后在tick 89重新抢占中速空调
+			policy: PolicyStrictPriority,
+			checkpoints: map[int]queueState{
+				8:   initial,
+				9:   {serving: []int{3, 4}, buffer: []int{3, 4, 2, 1}, warming: []int{}},
+				69:  {serving: []int{3, 2}, buffer: []int{3, 2, 1}, warming: []int{4}},
+				89:  {serving: []int{3, 4}, buffer: []int{3, 4, 2, 1}, warming: []int{}},
+				109: {serving: []int{2, 1}, buffer: []int{2, 1}, warming: []int{4, 3}},
+			},
+		},
+		{
+			// 服务边界两侧优先级不同，不进入时间片轮转，结果与严格优先级一致
+			policy: PolicyPriorityTimeSlice,
+			checkpoints: map[int]queueState{
+				8:   initial,
+				9:   {serving: []int{3, 4}, buffer: []int{3, 4, 2, 1}, warming: []int{}},
+				69:  {serving: []int{3, 2}, buffer: []int{3, 2, 1}, warming: []int{4}},
+				89:  {serving: []int{3, 4}, buffer: []int{3, 4, 2, 1}, warming: []int{}},
+				109: {serving: []int{2, 1}, buffer: []int{2, 1}, warming: []int{4, 3}},
+			},
+		},
+		{
+			// tick 9 剩余温差: 空调2为4度，空调4为6度，空调3为10度，空调1为11.7度；
+			// tick 49 空调2和空调4剩余温差相同，高速的空调4优先
+			policy: PolicyShortestDelta,
+			checkpoints: map[int]queueState{
+				8:  initial,
+				9:  {serving: []int{2, 4}, buffer: []int{2, 4, 3, 1}, warming: []int{}},
+				49: {serving: []int{4, 2}, buffer: []int{4, 2, 3, 1}, warming: []int{}},
+				69: {serving: []int{2, 3}, buffer: []int{2, 3, 1}, warming: []int{4}},
+				89: {serving: []int{4, 3}, buffer: []int{4, 3, 1}, warming: []int{2}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			s := newStepScheduler(t, tt.policy, config, acs)
+
+			for tick := 1; tick <= 400; tick++ {
+				if got := s.Step(1); got != tick {
+					t.Fatalf("Step 返回的tick计数为 %d，期望 %d", got, tick)
+				}
+
+				state := snapshotQueues(s)
+				checkQueueInvariants(t, tick, state, acs, config.MaxServing)
+
+				if want, ok := tt.checkpoints[tick]; ok && !reflect.DeepEqual(state, want) {
+					t.Fatalf("tick %d 队列状态为 %+v，期望 %+v", tick, state, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSchedulerStepTimeSliceYieldsToUnservedAC(t *testing.T) {
+	// 三台高速空调争用两个服务位置，第一次重排时已服务过的空调1让出服务
+	acs := []testAC{
+		{id: 1, speed: "high", target: 180},
+		{id: 2, speed: "high", target: 180},
+		{id: 3, speed: "high", target: 180},
+	}
+	s := newStepScheduler(t, PolicyPriorityTimeSlice, SchedulerConfig{MaxServing: 2}, acs)
+
+	s.Step(9)
+	want := queueState{serving: []int{2, 3}, buffer: []int{2, 3, 1}, warming: []int{}}
+	if state := snapshotQueues(s); !reflect.DeepEqual(state, want) {
+		t.Fatalf("tick 9 队列状态为 %+v，期望 %+v", state, want)
+	}
+	if snapshot := s.Snapshot(); snapshot.CurrentPriority != 1 {
+		t.Fatalf("时间片调度优先级为 %d，期望 1", snapshot.CurrentPriority)
+	}
+}
+
+func TestSchedulerStepAdvancesVirtualClock(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+	clock := NewVirtualClock(start)
+	s := NewACScheduler(SchedulerOptions{Clock: clock, DisablePersistence: true})
+
+	if got := s.Step(300); got != 300 {
+		t.Fatalf("Step 返回的tick计数为 %d，期望 300", got)
+	}
+	if want := start.Add(300 * DefaultTickInterval); !clock.Now().Equal(want) {
+		t.Fatalf("虚拟时钟为 %v，期望 %v", clock.Now(), want)
+	}
+}
+
+func TestSchedulerRequeuesWarmingACPastRewarmDelta(t *testing.T) {
+	// 回温一次超过回温温差（例如温差配置被调小）时空调也应重新请求服务，不能永远停在回温队列中
+	s := newStepScheduler(t, PolicyPriorityTimeSlice, SchedulerConfig{}, nil)
+	ac := &models.Scheduler{
+		ACID:            1,
+		RoomID:          1,
+		ACState:         3,
+		Mode:            "cooling",
+		Priority:        PriorityForSpeed("medium"),
+		CurrentSpeed:    "medium",
+		CurrentTemp:     195,
+		TargetTemp:      180,
+		EnvironmentTemp: 300,
+	}
+
+	s.mu.Lock()
+	s.schedulers[ac.ACID] = ac
+	s.warmingQueue = append(s.warmingQueue, ac)
+	s.updateWarmingQueue()
+	s.mu.Unlock()
+
+	want := queueState{serving: []int{}, buffer: []int{1}, warming: []int{}}
+	if state := snapshotQueues(s); !reflect.DeepEqual(state, want) {
+		t.Fatalf("队列状态为 %+v，期望 %+v", state, want)
+	}
+	if ac.ACState != 1 {
+		t.Fatalf("空调状态为 %d，期望 1", ac.ACState)
+	}
+}
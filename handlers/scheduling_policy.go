@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"bupt-hotel/models"
+)
+
+// 调度策略名称
+const (
+	PolicyFIFO              = "fifo"                // 先来先服务
+	PolicyStrictPriority    = "strict_priority"     // 严格优先级
+	PolicyPriorityTimeSlice = "priority_time_slice" // 优先级+时间片轮转（默认）
+	PolicyShortestDelta     = "shortest_delta"      // 剩余温差最小优先
+)
+
+// SchedulingPolicy 调度策略接口
+// 在每个调度周期内对缓冲队列重新排序，排序后缓冲队列的前几位将进入服务队列
+// 调用时调度器已持有锁，策略可以直接读写调度器的队列和时间片状态
+type SchedulingPolicy interface {
+	// Name 返回策略名称
+	Name() string
+	// Order 对调度器的缓冲队列进行排序
+	Order(s *ACScheduler)
+}
+
+// NewSchedulingPolicy 根据名称创建调度策略，名称为空时使用默认策略
+func NewSchedulingPolicy(name string) (SchedulingPolicy, error) {
+	switch name {
+	case "", PolicyPriorityTimeSlice:
+		return priorityTimeSlicePolicy{}, nil
+	case PolicyFIFO:
+		return fifoPolicy{}, nil
+	case PolicyStrictPriority:
+		return strictPriorityPolicy{}, nil
+	case PolicyShortestDelta:
+		return shortestDeltaPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("未知的调度策略: %s", name)
+	}
+}
+
+// priorityTimeSlicePolicy 优先级调度+同优先级时间片轮转
+type priorityTimeSlicePolicy struct{}
+
+func (priorityTimeSlicePolicy) Name() string { return PolicyPriorityTimeSlice }
+
+func (priorityTimeSlicePolicy) Order(s *ACScheduler) {
+	s.sortBufferQueue()
+}
+
+// fifoPolicy 先来先服务，保持请求到达缓冲队列的顺序
+type fifoPolicy struct{}
+
+func (fifoPolicy) Name() string { return PolicyFIFO }
+
+func (fifoPolicy) Order(s *ACScheduler) {
+	s.resetTimeSlice()
+	log.Printf("先来先服务策略：保持缓冲队列原有顺序，队列长度: %d", len(s.bufferQueue))
+}
+
+// strictPriorityPolicy 严格优先级，同优先级内保持到达顺序，不进行时间片轮转
+type strictPriorityPolicy struct{}
+
+func (strictPriorityPolicy) Name() string { return PolicyStrictPriority }
+
+func (strictPriorityPolicy) Order(s *ACScheduler) {
+	s.resetTimeSlice()
+	sort.SliceStable(s.bufferQueue, func(i, j int) bool {
+		return s.bufferQueue[i].Priority < s.bufferQueue[j].Priority
+	})
+	log.Printf("严格优先级策略：完成缓冲队列排序，队列长度: %d", len(s.bufferQueue))
+}
+
+// shortestDeltaPolicy 剩余温差最小的空调优先服务，温差相同时按优先级和ID排序
+type shortestDeltaPolicy struct{}
+
+func (shortestDeltaPolicy) Name() string { return PolicyShortestDelta }
+
+func (shortestDeltaPolicy) Order(s *ACScheduler) {
+	s.resetTimeSlice()
+	sort.SliceStable(s.bufferQueue, func(i, j int) bool {
+		di, dj := remainingDelta(s.bufferQueue[i]), remainingDelta(s.bufferQueue[j])
+		if di != dj {
+			return di < dj
+		}
+		if s.bufferQueue[i].Priority != s.bufferQueue[j].Priority {
+			return s.bufferQueue[i].Priority < s.bufferQueue[j].Priority
+		}
+		return s.bufferQueue[i].ACID < s.bufferQueue[j].ACID
+	})
+	log.Printf("剩余温差优先策略：完成缓冲队列排序，队列长度: %d", len(s.bufferQueue))
+}
+
+// remainingDelta 计算当前温度与目标温度差值的绝对值
+func remainingDelta(ac *models.Scheduler) int {
+	delta := ac.CurrentTemp - ac.TargetTemp
+	if delta < 0 {
+		delta = -delta
+	}
+	return delta
+}
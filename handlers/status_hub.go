@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"sync"
+
+	"bupt-hotel/events"
+	"bupt-hotel/models"
+)
+
+// StatusKey 空调状态订阅键，同一房间不同账单的状态互不可见
+type StatusKey struct {
+	RoomID int
+	BillID int
+}
+
+// StatusHub 空调状态推送中心
+// 通过事件总线接收调度器每个tick结束时各房间的空调状态，只有状态发生变化时才推送给订阅者
+type StatusHub struct {
+	mu          sync.Mutex
+	closed      bool
+	subscribers map[StatusKey]map[chan *ACStatusResponse]struct{} // 订阅键 -> 订阅者
+	latest      map[StatusKey]*ACStatusResponse                   // 订阅键 -> 最近一次发布的状态
+}
+
+var (
+	statusHubInstance *StatusHub
+	statusHubOnce     sync.Once
+)
+
+// GetStatusHub 获取空调状态推送中心单例，订阅全局事件总线
+func GetStatusHub() *StatusHub {
+	statusHubOnce.Do(func() {
+		statusHubInstance = NewStatusHub()
+		statusHubInstance.Listen(GetEventBus())
+	})
+	return statusHubInstance
+}
+
+// NewStatusHub 创建空调状态推送中心
+func NewStatusHub() *StatusHub {
+	return &StatusHub{
+		subscribers: make(map[StatusKey]map[chan *ACStatusResponse]struct{}),
+		latest:      make(map[StatusKey]*ACStatusResponse),
+	}
+}
+
+// Subscribe 订阅房间当前账单的空调状态，返回状态通道和取消订阅函数
+// 通道只保留最新的一条状态，消费不及时时旧状态会被覆盖；推送中心关闭后通道被关闭
+func (h *StatusHub) Subscribe(key StatusKey) (<-chan *ACStatusResponse, func()) {
+	ch := make(chan *ACStatusResponse, 1)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if h.subscribers[key] == nil {
+		h.subscribers[key] = make(map[chan *ACStatusResponse]struct{})
+	}
+	h.subscribers[key][ch] = struct{}{}
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subscribers[key][ch]; !ok {
+			return // 已取消或推送中心已关闭
+		}
+		delete(h.subscribers[key], ch)
+		if len(h.subscribers[key]) == 0 {
+			delete(h.subscribers, key)
+		}
+		close(ch)
+	}
+	return ch, cancel
+}
+
+// Latest 获取最近一次发布的空调状态，没有时返回nil
+func (h *StatusHub) Latest(key StatusKey) *ACStatusResponse {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.latest[key]
+}
+
+// Publish 发布调度器中所有空调的状态，与上一次发布的状态相同时不推送
+// 本次未发布的空调（已离开调度器）不再保留最近状态
+func (h *StatusHub) Publish(statuses map[StatusKey]*ACStatusResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, status := range statuses {
+		if last, ok := h.latest[key]; ok && !hasStatusChanged(*last, *status) {
+			continue
+		}
+		for ch := range h.subscribers[key] {
+			// 丢弃未被消费的旧状态，保证订阅者总能收到最新状态
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- status
+		}
+	}
+	h.latest = statuses
+}
+
+// Close 关闭推送中心，关闭所有订阅通道（服务关闭时调用）
+func (h *StatusHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for key, subscribers := range h.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+		delete(h.subscribers, key)
+	}
+}
+
+// Listen 订阅事件总线，每个tick结束时发布各房间的空调状态
+func (h *StatusHub) Listen(bus *events.Bus) func() {
+	return events.SubscribeTo(bus, "status_hub", func(tick SchedulerTick) {
+		h.Publish(tick.Statuses)
+	})
+}
+
+// schedulerToStatusResponse 将调度对象转换为状态响应格式
+func schedulerToStatusResponse(ac *models.Scheduler, acStatus int) *ACStatusResponse {
+	return &ACStatusResponse{
+		RoomID:             ac.RoomID,
+		ACStatus:           acStatus,
+		Speed:              ac.CurrentSpeed,
+		Mode:               ac.Mode,
+		TargetTemp:         ac.TargetTemp,
+		EnvironmentTemp:    ac.EnvironmentTemp,
+		CurrentTemp:        ac.CurrentTemp,
+		CurrentCost:        ac.CurrentCost,
+		TotalCost:          ac.TotalCost,
+		CurrentRunningTime: ac.CurrentRunningTime,
+		RunningTime:        ac.RunningTime,
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// ChangeStayRequest 续住/提前离店请求结构
+type ChangeStayRequest struct {
+	Days int `json:"days" binding:"required,min=1"` // 增加或减少的入住天数
+}
+
+// ExtendStay 续住：预计离店时间延后若干天，需检查延长的日期内房间没有被预订
+func ExtendStay(c *gin.Context) {
+	changeStay(c, "extend")
+}
+
+// ShortenStay 提前离店：预计离店时间提前若干天，至少保留一晚且不能早于当前时间
+func ShortenStay(c *gin.Context) {
+	changeStay(c, "shorten")
+}
+
+// changeStay 修改在住房间的预计离店时间，并以同一账单号记录 extend/shorten 操作
+// 房费在退房时按实际入住天数计算，这里只记录新的预计入住天数，不重新计算房费
+func changeStay(c *gin.Context, operationType string) {
+	roomID, err := strconv.Atoi(c.Param("room_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的房间ID",
+		})
+		return
+	}
+
+	var req ChangeStayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 没有权限时只能修改自己的房间
+	userID, _ := c.Get("user_id")
+	clientID := ""
+	if !middleware.HasPermission(c, models.PermRoomsModify) {
+		clientID = strconv.Itoa(userID.(int))
+	}
+
+	var room models.RoomInfo
+	var roomOperation models.RoomOperation
+	var before time.Time
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("room_id = ?", roomID).First(&room).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRoomNotFound
+			}
+			return err
+		}
+		if clientID != "" && room.ClientID != clientID {
+			return errRoomNotFound
+		}
+		if room.State != models.RoomOccupied {
+			return conflictf("房间 %d 未入住", roomID)
+		}
+
+		now := time.Now()
+		before = room.CheckoutTime
+		checkoutTime := room.CheckoutTime
+		if operationType == "extend" {
+			checkoutTime = checkoutTime.AddDate(0, 0, req.Days)
+			if !checkoutTime.After(now) {
+				return conflictf("续住后的离店时间 %s 早于当前时间", checkoutTime.Format("2006-01-02 15:04"))
+			}
+
+			// 检查延长的日期内房间没有被预订、房间类型仍有剩余
+			from := occupiedUntil(room)
+			to := dateOf(checkoutTime)
+			if to.After(from) {
+				if err := checkRoomBookable(tx, room, from, to, 0); err != nil {
+					return err
+				}
+			}
+		} else {
+			checkoutTime = checkoutTime.AddDate(0, 0, -req.Days)
+			if daysBetween(dateOf(room.CheckinTime), dateOf(checkoutTime)) < 1 {
+				return conflictf("至少需要入住一晚")
+			}
+			if !checkoutTime.After(now) {
+				return conflictf("离店时间 %s 早于当前时间，请直接办理退房", checkoutTime.Format("2006-01-02 15:04"))
+			}
+		}
+
+		billID, err := currentBillID(tx, roomID)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.RoomInfo{}).
+			Where("room_id = ? AND state = ? AND client_id = ?", roomID, models.RoomOccupied, room.ClientID).
+			Update("checkout_time", checkoutTime)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return conflictf("房间 %d 已退房", roomID)
+		}
+		room.CheckoutTime = checkoutTime
+
+		// 新的预计入住天数
+		days := daysBetween(dateOf(room.CheckinTime), dateOf(checkoutTime))
+		roomOperation = models.RoomOperation{
+			RoomID:        roomID,
+			BillID:        billID,
+			ClientID:      room.ClientID,
+			ClientName:    room.ClientName,
+			OperationType: operationType,
+			OperationTime: now,
+			CheckinTime:   room.CheckinTime,
+			CheckoutTime:  checkoutTime,
+			DailyRate:     room.DailyRate,
+			Deposit:       room.Deposit,
+			ActualDays:    days,
+		}
+		return tx.Create(&roomOperation).Error
+	})
+	if err != nil {
+		respondBookingError(c, err, "修改入住时间失败")
+		return
+	}
+	middleware.SetAuditChange(c,
+		gin.H{"bill_id": roomOperation.BillID, "checkout_time": before},
+		gin.H{"bill_id": roomOperation.BillID, "checkout_time": room.CheckoutTime, "days": roomOperation.ActualDays})
+
+	message := "续住成功"
+	if operationType == "shorten" {
+		message = "提前离店成功"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       message,
+		"bill_id":       roomOperation.BillID,
+		"room_id":       roomID,
+		"checkin_time":  room.CheckinTime,
+		"checkout_time": room.CheckoutTime,
+		"days":          roomOperation.ActualDays,
+		"daily_rate":    room.DailyRate,
+	})
+}
+
+// stayOperationTypes 开始在房间入住的操作类型：入住和换房换入
+var stayOperationTypes = []string{"checkin", "move_in"}
+
+// currentStay 获取房间当前入住的入住或换入记录
+func currentStay(db *gorm.DB, roomID int) (models.RoomOperation, error) {
+	var operation models.RoomOperation
+	err := db.Where("room_id = ? AND operation_type IN ?", roomID, stayOperationTypes).Order("operation_time DESC").First(&operation).Error
+	return operation, err
+}
+
+// currentBillID 获取房间当前入住的账单号
+func currentBillID(db *gorm.DB, roomID int) (int, error) {
+	operation, err := currentStay(db, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return operation.BillID, nil
+}
+
+// billStay 获取账单当前所在房间的入住或换入记录
+func billStay(db *gorm.DB, billID int) (models.RoomOperation, error) {
+	var operation models.RoomOperation
+	err := db.Where("bill_id = ? AND operation_type IN ?", billID, stayOperationTypes).Order("operation_time DESC").First(&operation).Error
+	return operation, err
+}
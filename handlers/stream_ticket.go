@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bupt-hotel/middleware"
+)
+
+// IssueStreamTicket 签发一次性连接票据，用于浏览器WebSocket和EventSource连接
+// 票据代表当前请求的用户令牌或设备，在有效期内只能使用一次
+func IssueStreamTicket(c *gin.Context) {
+	var ticket string
+	var expiresAt time.Time
+	var err error
+	if deviceID := c.GetInt("device_id"); deviceID != 0 {
+		ticket, expiresAt, err = middleware.IssueDeviceStreamTicket(deviceID)
+	} else {
+		value, _ := c.Get("claims")
+		ticket, expiresAt, err = middleware.IssueStreamTicket(value.(*middleware.Claims))
+	}
+	if err != nil {
+		log.Printf("签发连接票据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "签发连接票据失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "签发连接票据成功",
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+		"expires_in": int(middleware.StreamTicketTTL.Seconds()),
+	})
+}
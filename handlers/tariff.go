@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bupt-hotel/models"
+)
+
+// TariffPeriod 分时电价时段，时段为[StartHour, EndHour)，EndHour小于StartHour表示跨越零点
+type TariffPeriod struct {
+	Name       string  `yaml:"name" json:"name"`             // 时段名称，如 peak/off-peak
+	StartHour  int     `yaml:"start_hour" json:"start_hour"` // 开始小时（含）
+	EndHour    int     `yaml:"end_hour" json:"end_hour"`     // 结束小时（不含）
+	Multiplier float64 `yaml:"multiplier" json:"multiplier"` // 费率倍数
+}
+
+// Tariff 空调计费规则，调度器计费和退房报告均使用同一套规则
+// 空调在服务队列中且未达到目标温度时，每个tick按 风速费率 × 房间类型倍数 × 时段倍数 计费
+type Tariff struct {
+	SpeedRates          map[string]float64 `yaml:"speed_rates" json:"speed_rates"`                     // 各风速每个服务tick的费用（元）
+	RoomTypeMultipliers map[int]float64    `yaml:"room_type_multipliers" json:"room_type_multipliers"` // 房间类型ID -> 费率倍数，未配置为1
+	Periods             []TariffPeriod     `yaml:"periods" json:"periods"`                             // 分时时段，未命中任何时段时倍数为1
+	MinimumCharge       float64            `yaml:"minimum_charge" json:"minimum_charge"`               // 每个账单使用过空调时的最低消费
+}
+
+// DefaultTariff 默认计费规则：高风1元/tick，中风0.5元/tick，低风0.33元/tick，即每变化0.1°C计1元
+func DefaultTariff() Tariff {
+	return Tariff{
+		SpeedRates: map[string]float64{
+			"high":   1.0,
+			"medium": 0.5,
+			"low":    1.0 / 3.0,
+		},
+		RoomTypeMultipliers: map[int]float64{},
+	}
+}
+
+// Validate 校验计费规则
+func (t Tariff) Validate() error {
+	for _, speed := range []string{"high", "medium", "low"} {
+		rate, ok := t.SpeedRates[speed]
+		if !ok {
+			return fmt.Errorf("缺少风速 %s 的费率", speed)
+		}
+		if rate < 0 {
+			return fmt.Errorf("风速 %s 的费率不能为负数: %v", speed, rate)
+		}
+	}
+	for roomTypeID, multiplier := range t.RoomTypeMultipliers {
+		if multiplier < 0 {
+			return fmt.Errorf("房间类型 %d 的费率倍数不能为负数: %v", roomTypeID, multiplier)
+		}
+	}
+	for _, period := range t.Periods {
+		if period.StartHour < 0 || period.StartHour > 23 || period.EndHour < 0 || period.EndHour > 24 {
+			return fmt.Errorf("时段 %s 的小时范围无效: %d-%d", period.Name, period.StartHour, period.EndHour)
+		}
+		if period.Multiplier < 0 {
+			return fmt.Errorf("时段 %s 的费率倍数不能为负数: %v", period.Name, period.Multiplier)
+		}
+	}
+	if t.MinimumCharge < 0 {
+		return fmt.Errorf("最低消费不能为负数: %v", t.MinimumCharge)
+	}
+	return nil
+}
+
+// Rate 计算指定风速、房间类型和时间下每个服务tick的费率
+func (t Tariff) Rate(speed string, roomTypeID int, at time.Time) float64 {
+	rate, ok := t.SpeedRates[speed]
+	if !ok {
+		rate = t.SpeedRates["medium"]
+	}
+
+	if multiplier, ok := t.RoomTypeMultipliers[roomTypeID]; ok {
+		rate *= multiplier
+	}
+
+	if period := t.periodAt(at); period != nil {
+		rate *= period.Multiplier
+	}
+	return rate
+}
+
+// periodAt 返回时间所在的分时时段，没有命中时返回nil
+func (t Tariff) periodAt(at time.Time) *TariffPeriod {
+	hour := at.Hour()
+	for i := range t.Periods {
+		period := &t.Periods[i]
+		if period.StartHour <= period.EndHour {
+			if hour >= period.StartHour && hour < period.EndHour {
+				return period
+			}
+		} else if hour >= period.StartHour || hour < period.EndHour {
+			return period
+		}
+	}
+	return nil
+}
+
+// Settle 计算账单最终的空调费用：使用过空调但费用低于最低消费时按最低消费收取
+func (t Tariff) Settle(total float64) float64 {
+	if total > 0 && total < t.MinimumCharge {
+		total = t.MinimumCharge
+	}
+	return roundMoney(total)
+}
+
+// BillACUsage 根据空调状态记录计算账单累计的空调费用（未计最低消费）
+// 每条状态记录保存了该tick的计费金额，直接合计即可，不受重新开机和换房的影响
+func BillACUsage(db *gorm.DB, billID int) (float64, error) {
+	var total float64
+	if err := db.Model(&models.AirConditionerDetail{}).Where("bill_id = ?", billID).
+		Select("COALESCE(SUM(charge), 0)").Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+func TestBillACUsageAcrossRestarts(t *testing.T) {
+	setupTestDB(t)
+
+	const roomID = 101
+	billID := checkinTestRoom(t, roomID)
+
+	s := NewACScheduler(SchedulerOptions{Clock: NewVirtualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local))})
+	start := func(target int) *models.Scheduler {
+		ac := &models.Scheduler{
+			ACID:            1,
+			RoomID:          roomID,
+			BillID:          billID,
+			Mode:            "cooling",
+			Priority:        PriorityForSpeed("high"),
+			CurrentSpeed:    "high",
+			CurrentTemp:     300,
+			TargetTemp:      target,
+			EnvironmentTemp: 300,
+		}
+		s.AddRequest(ac)
+		return ac
+	}
+
+	// 第一次开机服务1个tick即达到目标温度，关机后还未移入回温队列时重新开机，
+	// 新的调度对象累计费用从0开始，与上一段的累计费用交替写入状态记录
+	first := start(299)
+	s.Step(1)
+	s.RemoveRequest(1)
+	second := start(180)
+	s.Step(30)
+	s.Stop()
+
+	usage, err := BillACUsage(database.DB, billID)
+	if err != nil {
+		t.Fatalf("计算空调费用失败: %v", err)
+	}
+	want := float64(first.TotalCost + second.TotalCost)
+	if first.TotalCost != 1 || math.Abs(usage-want) > 1e-3 {
+		t.Fatalf("空调费用为 %v，期望 %v（第一次开机 %v，第二次开机 %v）", usage, want, first.TotalCost, second.TotalCost)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+// 房间热模型名称
+const (
+	ThermalLinear    = "linear"    // 固定步长线性模型（默认）
+	ThermalNewtonian = "newtonian" // 牛顿冷却模型
+)
+
+// ThermalModel 房间热模型接口，决定每个tick房间温度的变化量
+// 返回值均为非负的温度变化量（*10），方向由调度器决定：
+// 服务中的空调朝目标温度变化，未服务的空调朝环境温度变化
+type ThermalModel interface {
+	// Name 返回模型名称
+	Name() string
+	// Serve 返回服务中的空调在本tick内朝目标温度的变化量
+	Serve(ac *models.Scheduler, tick int) int
+	// Drift 返回回温中的空调在本tick内朝环境温度的变化量
+	Drift(ac *models.Scheduler, tick int) int
+}
+
+// NewThermalModel 根据名称创建房间热模型，名称为空时使用线性模型
+// params 为按房间类型ID索引的热参数，仅牛顿冷却模型使用
+func NewThermalModel(name string, params map[int]RoomThermalParams) (ThermalModel, error) {
+	switch name {
+	case "", ThermalLinear:
+		return linearModel{}, nil
+	case ThermalNewtonian:
+		return NewNewtonianModel(params), nil
+	default:
+		return nil, fmt.Errorf("未知的房间热模型: %s", name)
+	}
+}
+
+// linearModel 固定步长模型：
+// 服务时高风每tick变化0.1度，中风每2个tick变化0.1度，低风每3个tick变化0.1度；回温时每2个tick变化0.1度
+type linearModel struct{}
+
+func (linearModel) Name() string { return ThermalLinear }
+
+func (linearModel) Serve(ac *models.Scheduler, tick int) int {
+	switch ac.CurrentSpeed {
+	case "high":
+		return 1
+	case "medium":
+		if tick%2 == 0 {
+			return 1
+		}
+	case "low":
+		if tick%3 == 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (linearModel) Drift(ac *models.Scheduler, tick int) int {
+	if tick%2 == 0 {
+		return 1
+	}
+	return 0
+}
+
+// RoomThermalParams 房间类型的热参数
+type RoomThermalParams struct {
+	Insulation  float64 `yaml:"insulation"`   // 散热系数：每tick向环境温度靠拢的温差比例，越小保温越好
+	ThermalMass float64 `yaml:"thermal_mass"` // 热容倍数：越大房间温度变化越慢，标准房间为1
+}
+
+// DefaultRoomThermalParams 未配置房间类型时使用的热参数
+// 房间温度的平衡点为偏离环境温度 空调能力/散热系数，与热容无关；
+// 低速在该散热系数下可维持约33度的温差，大于目标温度范围（16-30度）与环境温度之间可能出现的最大温差
+var DefaultRoomThermalParams = RoomThermalParams{Insulation: 0.001, ThermalMass: 1.0}
+
+// speedPower 各风速下空调在标准房间中每tick的制冷/制热能力（*10）
+var speedPower = map[string]float64{
+	"high":   1.0,
+	"medium": 0.5,
+	"low":    1.0 / 3.0,
+}
+
+// NewtonianModel 牛顿冷却模型：
+// 服务时每tick温度变化 = (空调能力 - 散热系数*|当前温度-环境温度|) / 热容（远离环境温度时散热抵消制冷/制热）
+// 回温时每tick温度变化 = 散热系数*|当前温度-环境温度| / 热容
+// 不足0.1度的变化量按空调累计，累计满0.1度时才体现到温度上
+type NewtonianModel struct {
+	mu         sync.Mutex
+	params     map[int]RoomThermalParams // 房间类型ID -> 热参数
+	serveCarry map[int]float64           // ACID -> 服务时累计的变化量
+	driftCarry map[int]float64           // ACID -> 回温时累计的变化量
+}
+
+// NewNewtonianModel 创建牛顿冷却模型
+func NewNewtonianModel(params map[int]RoomThermalParams) *NewtonianModel {
+	if params == nil {
+		params = make(map[int]RoomThermalParams)
+	}
+	return &NewtonianModel{
+		params:     params,
+		serveCarry: make(map[int]float64),
+		driftCarry: make(map[int]float64),
+	}
+}
+
+// LoadNewtonianModel 从房间类型表加载热参数并创建牛顿冷却模型
+func LoadNewtonianModel() (*NewtonianModel, error) {
+	var roomTypes []models.RoomType
+	if err := database.DB.Find(&roomTypes).Error; err != nil {
+		return nil, err
+	}
+
+	params := make(map[int]RoomThermalParams, len(roomTypes))
+	for _, rt := range roomTypes {
+		params[rt.ID] = RoomThermalParams{Insulation: rt.Insulation, ThermalMass: rt.ThermalMass}
+	}
+	return NewNewtonianModel(params), nil
+}
+
+func (m *NewtonianModel) Name() string { return ThermalNewtonian }
+
+// paramsFor 获取空调所在房间类型的热参数
+func (m *NewtonianModel) paramsFor(ac *models.Scheduler) RoomThermalParams {
+	p, ok := m.params[ac.RoomTypeID]
+	if !ok {
+		p = DefaultRoomThermalParams
+	}
+	if p.ThermalMass <= 0 {
+		p.ThermalMass = DefaultRoomThermalParams.ThermalMass
+	}
+	return p
+}
+
+func (m *NewtonianModel) Serve(ac *models.Scheduler, tick int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.paramsFor(ac)
+	power := speedPower[ac.CurrentSpeed]
+	// 当前温度偏离环境温度的方向与空调工作方向一致时，散热抵消一部分空调能力
+	gap := float64(ac.CurrentTemp - ac.EnvironmentTemp)
+	if (ac.Mode == "heating" && gap > 0) || (ac.Mode == "cooling" && gap < 0) {
+		power -= p.Insulation * math.Abs(gap)
+	}
+	change := power / p.ThermalMass
+	if change < 0 {
+		change = 0
+	}
+
+	delete(m.driftCarry, ac.ACID)
+	return takeWhole(m.serveCarry, ac.ACID, change)
+}
+
+func (m *NewtonianModel) Drift(ac *models.Scheduler, tick int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.paramsFor(ac)
+	change := p.Insulation * math.Abs(float64(ac.CurrentTemp-ac.EnvironmentTemp)) / p.ThermalMass
+
+	delete(m.serveCarry, ac.ACID)
+	return takeWhole(m.driftCarry, ac.ACID, change)
+}
+
+// takeWhole 将本tick变化量累加到carry中，取出整数部分作为温度变化量
+func takeWhole(carry map[int]float64, acID int, change float64) int {
+	total := carry[acID] + change
+	whole := math.Floor(total)
+	carry[acID] = total - whole
+	return int(whole)
+}
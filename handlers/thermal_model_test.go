@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"bupt-hotel/models"
+)
+
+func TestNewtonianModelReachesTarget(t *testing.T) {
+	// 种子数据中的房间类型按顺序获得ID 1-5，ID 0未配置，使用默认热参数
+	params := make(map[int]RoomThermalParams)
+	for i, rt := range models.GetDefaultRoomTypes() {
+		params[i+1] = RoomThermalParams{Insulation: rt.Insulation, ThermalMass: rt.ThermalMass}
+	}
+	roomTypeIDs := []int{0}
+	for id := range params {
+		roomTypeIDs = append(roomTypeIDs, id)
+	}
+
+	// 目标温度范围160-300与种子数据环境温度（100-250）之间的最大温差
+	cases := []struct {
+		name            string
+		mode            string
+		environmentTemp int
+		targetTemp      int
+	}{
+		{name: "heating", mode: "heating", environmentTemp: 100, targetTemp: 300},
+		{name: "cooling", mode: "cooling", environmentTemp: 250, targetTemp: 160},
+	}
+
+	const maxTicks = 5000
+	for _, roomTypeID := range roomTypeIDs {
+		for _, speed := range []string{"high", "medium", "low"} {
+			for _, tc := range cases {
+				ac := &models.Scheduler{
+					ACID:            1,
+					RoomID:          1,
+					RoomTypeID:      roomTypeID,
+					Mode:            tc.mode,
+					Priority:        PriorityForSpeed(speed),
+					CurrentSpeed:    speed,
+					CurrentTemp:     tc.environmentTemp,
+					TargetTemp:      tc.targetTemp,
+					EnvironmentTemp: tc.environmentTemp,
+				}
+				s := NewACScheduler(SchedulerOptions{
+					Clock:              NewVirtualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)),
+					Thermal:            NewNewtonianModel(params),
+					DisablePersistence: true,
+				})
+				s.AddRequest(ac)
+
+				// 调度器与测试在同一协程中运行，可以直接读取提交的调度对象
+				for tick := 0; tick < maxTicks && ac.CurrentTemp != tc.targetTemp; {
+					tick = s.Step(1)
+				}
+
+				if ac.CurrentTemp != tc.targetTemp || ac.ACState != 3 {
+					t.Errorf("房间类型 %d %s %s: %d个tick后温度停在 %d，未达到目标温度 %d",
+						roomTypeID, speed, tc.name, maxTicks, ac.CurrentTemp, tc.targetTemp)
+				}
+			}
+		}
+	}
+}
+
+func TestNewtonianModelThermalMass(t *testing.T) {
+	// 热容翻倍时服务和回温的变化量都减半
+	model := NewNewtonianModel(map[int]RoomThermalParams{
+		1: {Insulation: 0.001, ThermalMass: 1},
+		2: {Insulation: 0.001, ThermalMass: 2},
+	})
+
+	serve := func(roomTypeID int) int {
+		ac := &models.Scheduler{ACID: roomTypeID, RoomTypeID: roomTypeID, Mode: "heating", CurrentSpeed: "high", CurrentTemp: 200, EnvironmentTemp: 100}
+		total := 0
+		for tick := 1; tick <= 100; tick++ {
+			total += model.Serve(ac, tick)
+		}
+		return total
+	}
+	drift := func(roomTypeID int) int {
+		ac := &models.Scheduler{ACID: roomTypeID, RoomTypeID: roomTypeID, CurrentTemp: 200, EnvironmentTemp: 100}
+		total := 0
+		for tick := 1; tick <= 100; tick++ {
+			total += model.Drift(ac, tick)
+		}
+		return total
+	}
+
+	// 温差10度时：服务每tick (1-0.1)/热容，回温每tick 0.1/热容（单位0.1度），允许浮点累计误差1
+	checkTotals(t, "服务", serve(1), serve(2), 90, 45)
+	checkTotals(t, "回温", drift(1), drift(2), 10, 5)
+}
+
+// checkTotals 检查两种热容下100个tick的累计变化量
+func checkTotals(t *testing.T, name string, got1, got2, want1, want2 int) {
+	t.Helper()
+
+	if abs(got1-want1) > 1 || abs(got2-want2) > 1 {
+		t.Fatalf("%s100个tick的变化量为 %d 和 %d，期望 %d 和 %d", name, got1, got2, want1, want2)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
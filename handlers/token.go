@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// refreshTokenTTL 刷新令牌的有效期，每次刷新时轮换
+var refreshTokenTTL = 7 * 24 * time.Hour
+
+// errRefreshTokenReused 已轮换的刷新令牌被再次使用，会话已被吊销
+var errRefreshTokenReused = errors.New("刷新令牌已被使用")
+
+// errRefreshTokenInvalid 刷新令牌不存在、已吊销、已过期或账户已停用
+var errRefreshTokenInvalid = errors.New("无效的刷新令牌")
+
+// SetRefreshTokenTTL 设置刷新令牌的有效期
+func SetRefreshTokenTTL(ttl time.Duration) {
+	if ttl > 0 {
+		refreshTokenTTL = ttl
+	}
+}
+
+// RefreshTokenRequest 刷新令牌请求结构
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenPair 登录或刷新时签发的访问令牌和刷新令牌
+type TokenPair struct {
+	Token            string `json:"token"`              // 访问令牌（JWT）
+	RefreshToken     string `json:"refresh_token"`      // 刷新令牌，只能使用一次
+	ExpiresIn        int    `json:"expires_in"`         // 访问令牌有效期（秒）
+	RefreshExpiresIn int    `json:"refresh_expires_in"` // 刷新令牌有效期（秒）
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌和刷新令牌，旧的刷新令牌随即失效
+// 已轮换的刷新令牌再次使用时视为令牌泄露，吊销整个会话
+func RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var tokens *TokenPair
+	var user models.User
+	var reused models.RefreshToken
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Where("token_hash = ?", hashToken(req.RefreshToken)).First(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRefreshTokenInvalid
+			}
+			return err
+		}
+		if current.State == models.RefreshTokenRotated {
+			reused = current
+			return nil
+		}
+		if current.State != models.RefreshTokenActive || time.Now().After(current.ExpiresAt) {
+			return errRefreshTokenInvalid
+		}
+
+		if err := tx.First(&user, current.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRefreshTokenInvalid
+			}
+			return err
+		}
+		if user.Disabled {
+			return errRefreshTokenInvalid
+		}
+
+		// 条件更新：同一个刷新令牌只能轮换一次
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND state = ?", current.ID, models.RefreshTokenActive).
+			Update("state", models.RefreshTokenRotated)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenInvalid
+		}
+
+		var err error
+		tokens, err = issueTokens(tx, user, current.SessionID)
+		return err
+	})
+
+	// 重复使用已轮换的刷新令牌：单独提交吊销，不随刷新失败而回滚
+	if err == nil && reused.ID != 0 {
+		log.Printf("用户 %d 的会话 %s 中已轮换的刷新令牌被再次使用，吊销该会话", reused.UserID, reused.SessionID)
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			return revokeRefreshTokens(tx, "session_id", reused.SessionID)
+		})
+		if err == nil {
+			err = errRefreshTokenReused
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, errRefreshTokenInvalid) || errors.Is(err, errRefreshTokenReused) {
+			message := "无效的刷新令牌，请重新登录"
+			if errors.Is(err, errRefreshTokenReused) {
+				message = "刷新令牌已被使用，会话已吊销，请重新登录"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": message,
+			})
+			return
+		}
+		log.Printf("刷新令牌失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "刷新令牌失败",
+		})
+		return
+	}
+	middleware.SetAuditActor(c, user.ID, user.Username, user.Identity)
+	middleware.SetAuditEntity(c, models.AuditEntityUser, user.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "刷新令牌成功",
+		"token":              tokens.Token,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_in": tokens.RefreshExpiresIn,
+	})
+}
+
+// Logout 退出登录：吊销当前访问令牌和所在会话的刷新令牌
+func Logout(c *gin.Context) {
+	value, _ := c.Get("claims")
+	claims := value.(*middleware.Claims)
+	middleware.SetAuditEntity(c, models.AuditEntityUser, claims.UserID)
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := revokeAccessToken(tx, claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+		if claims.SessionID == "" {
+			return nil
+		}
+		return revokeRefreshTokens(tx, "session_id", claims.SessionID)
+	})
+	if err != nil {
+		log.Printf("退出登录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "退出登录失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "退出登录成功",
+	})
+}
+
+// RevokeUserTokens 吊销用户的所有会话（需要 users:manage 权限），用户需要重新登录
+func RevokeUserTokens(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "用户不存在",
+		})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return revokeRefreshTokens(tx, "user_id", userID)
+	})
+	if err != nil {
+		log.Printf("吊销用户 %d 的令牌失败: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "吊销令牌失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已吊销用户的所有会话",
+		"user_id": userID,
+	})
+}
+
+// issueTokens 为用户签发访问令牌和刷新令牌，sessionID为空时开始新的会话
+func issueTokens(tx *gorm.DB, user models.User, sessionID string) (*TokenPair, error) {
+	if sessionID == "" {
+		id, err := randomToken(16)
+		if err != nil {
+			return nil, err
+		}
+		sessionID = id
+	}
+
+	accessToken, claims, err := middleware.GenerateToken(user.ID, user.Username, user.Identity, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: hashToken(refreshToken),
+		AccessJTI: claims.ID,
+		State:     models.RefreshTokenActive,
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		Token:            accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int(middleware.AccessTokenTTL().Seconds()),
+		RefreshExpiresIn: int(refreshTokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeRefreshTokens 吊销column（session_id或user_id）匹配的所有刷新令牌，
+// 并将与之同时签发、尚未过期的访问令牌加入吊销列表
+func revokeRefreshTokens(tx *gorm.DB, column string, value interface{}) error {
+	now := time.Now()
+	ttl := middleware.AccessTokenTTL()
+
+	var recent []models.RefreshToken
+	if err := tx.Where(column+" = ? AND created_at > ?", value, now.Add(-ttl)).Find(&recent).Error; err != nil {
+		return err
+	}
+	for _, token := range recent {
+		if err := revokeAccessToken(tx, token.AccessJTI, token.UserID, token.CreatedAt.Add(ttl)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.RefreshToken{}).
+		Where(column+" = ? AND state <> ?", value, models.RefreshTokenRevoked).
+		Update("state", models.RefreshTokenRevoked).Error; err != nil {
+		return err
+	}
+
+	// 清理已过期的吊销记录和刷新令牌
+	if err := tx.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("expires_at < ?", now).Delete(&models.RefreshToken{}).Error
+}
+
+// revokeAccessToken 将访问令牌的jti加入吊销列表
+func revokeAccessToken(tx *gorm.DB, jti string, userID int, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// randomToken 生成n字节的随机令牌，以十六进制字符串表示
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken 计算令牌的SHA-256哈希，数据库中只保存哈希
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
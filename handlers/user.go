@@ -4,19 +4,35 @@ import (
 	"bupt-hotel/database"
 	"bupt-hotel/middleware"
 	"bupt-hotel/models"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// errUsernameTaken 用户名已存在
+var errUsernameTaken = errors.New("用户名已存在")
+
 // RegisterRequest 注册请求结构
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
-	Identity string `json:"identity" binding:"required"` // customer, administrator
+	Identity string `json:"identity"` // 公开注册只能为 customer，可省略
 }
 
+// dummyPasswordHash 用户不存在时用于比较密码的哈希，使用户不存在和密码错误的响应时间相同，无法据此探测用户名
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("bupt-hotel-dummy-password"), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("生成比较用的密码哈希失败: %v", err)
+	}
+	return hash
+})
+
 // LoginRequest 登录请求结构
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -32,46 +48,24 @@ func Register(c *gin.Context) {
 		})
 		return
 	}
+	middleware.SetAuditActor(c, 0, req.Username, "")
 
-	// 验证身份类型
-	if req.Identity != "customer" && req.Identity != "administrator" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "身份类型必须是 customer 或 administrator",
+	// 公开注册只能创建客户账户，员工账户由管理员创建
+	if req.Identity != "" && req.Identity != models.RoleCustomer {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "公开注册只能创建客户账户",
 		})
 		return
 	}
 
-	// 检查用户名是否已存在
-	var existingUser models.User
-	if err := database.DB.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{
-			"error": "用户名已存在",
-		})
-		return
-	}
-
-	// 加密密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	user, err := createUser(req.Username, req.Password, models.RoleCustomer)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "密码加密失败",
-		})
-		return
-	}
-
-	// 创建用户
-	user := models.User{
-		Username: req.Username,
-		Password: string(hashedPassword),
-		Identity: req.Identity,
-	}
-
-	if err := database.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "用户创建失败",
-		})
+		respondCreateUserError(c, err)
 		return
 	}
+	middleware.SetAuditActor(c, user.ID, user.Username, user.Identity)
+	middleware.SetAuditEntity(c, models.AuditEntityUser, user.ID)
+	middleware.SetAuditChange(c, nil, userInfo(*user))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "用户注册成功",
@@ -89,9 +83,29 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	middleware.SetAuditActor(c, 0, req.Username, "")
+
+	// 用户名或IP处于锁定期时不校验密码
+	ip := c.ClientIP()
+	remaining, err := loginLockRemaining(req.Username, ip)
+	if err != nil {
+		log.Printf("检查登录锁定失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "登录失败",
+		})
+		return
+	}
+	if remaining > 0 {
+		countLockedReject(req.Username, ip)
+		respondLoginLocked(c, remaining)
+		return
+	}
+
 	// 查找用户
 	var user models.User
 	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
+		recordLoginFailure(req.Username, ip, models.LoginFailureUnknownUser)
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "用户名或密码错误",
 		})
@@ -99,15 +113,32 @@ func Login(c *gin.Context) {
 	}
 
 	// 验证密码
+	middleware.SetAuditEntity(c, models.AuditEntityUser, user.ID)
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		recordLoginFailure(req.Username, ip, models.LoginFailureBadPassword)
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "用户名或密码错误",
 		})
 		return
 	}
 
-	// 生成JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Username, user.Identity)
+	if user.Disabled {
+		recordLoginFailure(req.Username, ip, models.LoginFailureDisabled)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "账户已停用",
+		})
+		return
+	}
+
+	// 登录成功后清除用户名的失败次数，IP的失败次数按时间窗口自然过期
+	if err := clearLoginThrottle(database.DB, models.LoginThrottleUsername, user.Username); err != nil {
+		log.Printf("清除用户 %s 的登录失败次数失败: %v", user.Username, err)
+	}
+
+	middleware.SetAuditActor(c, user.ID, user.Username, user.Identity)
+
+	// 签发访问令牌和刷新令牌，开始新的会话
+	tokens, err := issueTokens(database.DB, user, "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "token生成失败",
@@ -116,12 +147,61 @@ func Login(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "登录成功",
-		"token":   token,
-		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"identity": user.Identity,
-		},
+		"message":            "登录成功",
+		"token":              tokens.Token,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_in": tokens.RefreshExpiresIn,
+		"user":               userInfo(user),
 	})
 }
+
+// createUser 创建用户，密码以bcrypt加密存储，用户名已存在时返回errUsernameTaken
+func createUser(username, password, identity string) (*models.User, error) {
+	var count int64
+	if err := database.DB.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errUsernameTaken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("密码加密失败: %w", err)
+	}
+
+	user := &models.User{
+		Username: username,
+		Password: string(hashedPassword),
+		Identity: identity,
+	}
+	if err := database.DB.Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// respondCreateUserError 按错误类型返回创建用户失败的响应
+func respondCreateUserError(c *gin.Context, err error) {
+	if errors.Is(err, errUsernameTaken) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "用户名已存在",
+		})
+		return
+	}
+	log.Printf("用户创建失败: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "用户创建失败",
+	})
+}
+
+// userInfo 返回给客户端的用户信息，不包含密码
+func userInfo(user models.User) gin.H {
+	return gin.H{
+		"id":       user.ID,
+		"username": user.Username,
+		"identity": user.Identity,
+		"disabled": user.Disabled,
+	}
+}
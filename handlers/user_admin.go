@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+)
+
+// CreateUserRequest 管理员创建用户请求结构
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Identity string `json:"identity" binding:"required"` // 角色名称
+}
+
+// ChangeRoleRequest 修改用户身份请求结构
+type ChangeRoleRequest struct {
+	Identity string `json:"identity" binding:"required"`
+}
+
+// ResetPasswordRequest 重置密码请求结构
+type ResetPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// errSelfModification 管理员不能停用自己或修改自己的身份，避免失去管理权限
+var errSelfModification = errors.New("不能停用自己或修改自己的身份")
+
+// errRoleNotCovered 不能分配或管理权限超出自己的角色
+var errRoleNotCovered = errors.New("不能分配或管理权限超出自己的角色")
+
+// GetUsers 获取用户列表（需要 users:manage 权限），可按identity和disabled筛选
+func GetUsers(c *gin.Context) {
+	query := database.DB.Order("id ASC")
+	if identity := c.Query("identity"); identity != "" {
+		query = query.Where("identity = ?", identity)
+	}
+	if disabled := c.Query("disabled"); disabled != "" {
+		value, err := strconv.ParseBool(disabled)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "disabled 必须是 true 或 false",
+			})
+			return
+		}
+		query = query.Where("disabled = ?", value)
+	}
+
+	var users []models.User
+	if err := query.Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取用户列表失败",
+		})
+		return
+	}
+
+	list := make([]gin.H, 0, len(users))
+	for _, user := range users {
+		list = append(list, userInfo(user))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取用户列表成功",
+		"users":   list,
+	})
+}
+
+// CreateUser 创建用户（需要 users:manage 权限），可以创建员工账户
+func CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if !checkAssignableRole(c, req.Identity) {
+		return
+	}
+
+	user, err := createUser(req.Username, req.Password, req.Identity)
+	if err != nil {
+		respondCreateUserError(c, err)
+		return
+	}
+	middleware.SetAuditEntity(c, models.AuditEntityUser, user.ID)
+	middleware.SetAuditChange(c, nil, userInfo(*user))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "用户创建成功",
+		"user":    userInfo(*user),
+	})
+}
+
+// ChangeUserRole 修改用户身份（需要 users:manage 权限），用户的现有会话随即吊销，重新登录后按新身份授权
+func ChangeUserRole(c *gin.Context) {
+	var req ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if !checkAssignableRole(c, req.Identity) {
+		return
+	}
+
+	updateUser(c, "修改身份", true, map[string]interface{}{"identity": req.Identity})
+}
+
+// DisableUser 停用账户（需要 users:manage 权限），用户的现有会话随即吊销
+func DisableUser(c *gin.Context) {
+	updateUser(c, "停用账户", true, map[string]interface{}{"disabled": true})
+}
+
+// EnableUser 启用账户（需要 users:manage 权限）
+func EnableUser(c *gin.Context) {
+	updateUser(c, "启用账户", false, map[string]interface{}{"disabled": false})
+}
+
+// ResetUserPassword 重置用户密码（需要 users:manage 权限），用户的现有会话随即吊销
+func ResetUserPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "密码加密失败",
+		})
+		return
+	}
+
+	updateUser(c, "重置密码", true, map[string]interface{}{"password": string(hashedPassword)})
+}
+
+// updateUser 修改URL中指定的用户，revoke为true时同时吊销该用户的所有会话
+// 停用账户和修改身份不能作用于当前登录的管理员自己，也不能管理权限超出自己的用户
+func updateUser(c *gin.Context, action string, revoke bool, updates map[string]interface{}) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+
+	currentUserID, _ := c.Get("user_id")
+	_, changesIdentity := updates["identity"]
+	_, changesDisabled := updates["disabled"]
+	if userID == currentUserID && (changesIdentity || changesDisabled) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": errSelfModification.Error(),
+		})
+		return
+	}
+
+	caller, err := middleware.CurrentRole(c)
+	if err != nil {
+		log.Printf("加载角色权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + "失败",
+		})
+		return
+	}
+
+	var user models.User
+	var before gin.H
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		before = userInfo(user)
+		var target models.Role
+		if err := tx.Where("name = ?", user.Identity).Limit(1).Find(&target).Error; err != nil {
+			return err
+		}
+		if !roleCovers(caller, target) {
+			return errRoleNotCovered
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		if revoke {
+			return revokeRefreshTokens(tx, "user_id", userID)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+			return
+		}
+		if errors.Is(err, errRoleNotCovered) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		log.Printf("%s失败: 用户 %d: %v", action, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + "失败",
+		})
+		return
+	}
+	middleware.SetAuditChange(c, before, userInfo(user))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": action + "成功",
+		"user":    userInfo(user),
+	})
+}
+
+// checkAssignableRole 检查角色存在且当前用户可以分配该角色；失败时已写入响应
+func checkAssignableRole(c *gin.Context, name string) bool {
+	var role models.Role
+	if err := database.DB.Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "角色不存在: " + name,
+			})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "加载角色失败",
+		})
+		return false
+	}
+
+	caller, err := middleware.CurrentRole(c)
+	if err != nil {
+		log.Printf("加载角色权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "加载权限失败",
+		})
+		return false
+	}
+	if !roleCovers(caller, role) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": errRoleNotCovered.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// roleCovers caller是否拥有target的全部权限，防止通过分配角色提升权限
+func roleCovers(caller, target models.Role) bool {
+	if caller.HasPermission(models.PermissionAll) {
+		return true
+	}
+	for _, permission := range target.Permissions {
+		if permission == models.PermissionAll {
+			return false
+		}
+		prefix := strings.TrimSuffix(permission, "*")
+		for defined := range models.PermissionDescriptions {
+			if (defined == permission || (strings.HasSuffix(permission, ":*") && strings.HasPrefix(defined, prefix))) &&
+				!caller.HasPermission(defined) {
+				return false
+			}
+		}
+	}
+	return true
+}
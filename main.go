@@ -4,37 +4,81 @@ import (
 	"bupt-hotel/database"
 	"bupt-hotel/handlers"
 	"bupt-hotel/middleware"
+	"bupt-hotel/models"
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// 子命令：场景仿真
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		if err := runSimulate(os.Args[2:]); err != nil {
+			log.SetOutput(os.Stderr)
+			log.Fatal("场景仿真失败:", err)
+		}
+		return
+	}
+
 	// 加载配置
-	config := LoadConfig()
+	config, err := LoadConfig()
+	if err != nil {
+		log.Fatal("配置加载失败:", err)
+	}
 
-	// 初始化JWT
-	middleware.InitJWT(config.JWTSecret)
+	// 初始化JWT和令牌有效期
+	middleware.InitJWT(config.JWTSecret, config.AccessTokenTTL)
+	handlers.SetRefreshTokenTTL(config.RefreshTokenTTL)
 
 	// 初始化数据库
 	if err := database.InitDatabase(config.DatabasePath); err != nil {
 		log.Fatal("数据库初始化失败:", err)
 	}
 
-	// 启动全局调度器
+	// 配置全局调度器
+	policy, err := handlers.NewSchedulingPolicy(config.SchedulerPolicy)
+	if err != nil {
+		log.Fatal("调度策略配置错误:", err)
+	}
+	handlers.GetScheduler().SetPolicy(policy)
+	if err := handlers.GetScheduler().SetConfig(config.Scheduler); err != nil {
+		log.Fatal("调度器参数配置错误:", err)
+	}
+	if err := handlers.GetScheduler().SetTariff(config.Tariff); err != nil {
+		log.Fatal("计费规则配置错误:", err)
+	}
+
+	thermal, err := loadThermalModel(config.ThermalModel)
+	if err != nil {
+		log.Fatal("房间热模型配置错误:", err)
+	}
+	handlers.GetScheduler().SetThermalModel(thermal)
+
+	// 根据数据库记录恢复重启前仍在运行的空调
+	if _, err := handlers.GetScheduler().RecoverState(); err != nil {
+		log.Printf("恢复空调调度状态失败: %v", err)
+	}
 
 	// 设置Gin模式
 	gin.SetMode(gin.DebugMode)
 	// gin.SetMode(gin.ReleaseMode)
 
-	// 创建Gin路由器
-	r := gin.Default()
+	// 创建Gin路由器，访问日志隐藏查询参数中的令牌和连接票据
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery())
 
 	// 添加CORS中间件
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Device-Key")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -52,18 +96,22 @@ func main() {
 
 	// API路由组
 	api := r.Group("/api")
+	api.Use(middleware.AuditLogger()) // 记录所有修改数据的请求
 	{
 		// 公开路由（无需认证）
 		public := api.Group("/public")
 		{
-			public.POST("/register", handlers.Register) // 用户注册
-			public.POST("/login", handlers.Login)       // 用户登录
+			public.POST("/register", handlers.Register)    // 用户注册
+			public.POST("/login", handlers.Login)          // 用户登录
+			public.POST("/refresh", handlers.RefreshToken) // 刷新令牌
 		}
 
 		// 需要认证的路由
 		auth := api.Group("/auth")
 		auth.Use(middleware.AuthMiddleware())
 		{
+			auth.POST("/logout", handlers.Logout) // 退出登录
+
 			// 房间相关路由
 			rooms := auth.Group("/rooms")
 			{
@@ -73,26 +121,85 @@ func main() {
 				rooms.GET("/my", handlers.GetMyRooms)                   // 获取我的房间
 				rooms.POST("/book", handlers.BookRoom)                  // 订房
 				rooms.POST("/:room_id/checkout", handlers.CheckoutRoom) // 退房
+				rooms.POST("/:room_id/extend", handlers.ExtendStay)     // 续住
+				rooms.POST("/:room_id/shorten", handlers.ShortenStay)   // 提前离店
+				rooms.POST("/:room_id/move", handlers.MoveRoom)         // 换房
 			}
 
-			// 空调相关路由
-			ac := auth.Group("/airconditioner")
+			// 预订相关路由
+			reservations := auth.Group("/reservations")
 			{
+				reservations.POST("", handlers.CreateReservation)                   // 创建预订
+				reservations.GET("/my", handlers.GetMyReservations)                 // 获取我的预订
+				reservations.GET("/availability", handlers.GetAvailabilityCalendar) // 房态日历
+				reservations.POST("/:id/cancel", handlers.CancelReservation)        // 取消预订
+				reservations.POST("/:id/checkin", handlers.CheckinReservation)      // 预订入住
+			}
 
-				ac.PUT("/:room_id", handlers.ControlAirConditioner)         // 控制空调
-				ac.GET("/:room_id/status", handlers.GetACStatusLongPolling) // 长轮询获取空调状态
+			// 账单相关路由
+			folios := auth.Group("/folios")
+			{
+				folios.GET("/:bill_id", handlers.GetFolio)                   // 获取账单明细
+				folios.GET("/:bill_id/invoice", handlers.DownloadInvoice)    // 下载账单
+				folios.GET("/:bill_id/ac-report", handlers.DownloadACReport) // 下载空调使用详单
 			}
 		}
 
-		// 管理员路由
+		// 空调相关路由：入住客人、拥有 scheduler:override 权限的用户、绑定该房间空调的设备密钥可以访问
+		ac := api.Group("/auth/airconditioner")
+		ac.Use(middleware.RoomAuthMiddleware(), middleware.RequireRoomAccess())
+		{
+			ac.PUT("/:room_id", handlers.ControlAirConditioner)         // 控制空调
+			ac.GET("/:room_id/status", handlers.GetACStatusLongPolling) // 长轮询获取空调状态（兼容旧版房间面板）
+			ac.GET("/:room_id/ws", handlers.ACStatusWebSocket)          // WebSocket实时推送空调状态
+		}
+
+		// 连接票据：浏览器WebSocket和EventSource无法设置请求头，先用令牌换取一次性票据再通过 ?ticket= 连接
+		api.POST("/stream-ticket", middleware.RoomAuthMiddleware(), handlers.IssueStreamTicket)
+
+		// 设备路由：使用 X-Device-Key 设备密钥认证
+		device := api.Group("/device")
+		device.Use(middleware.DeviceAuthMiddleware())
+		{
+			device.GET("/me", handlers.GetCurrentDevice) // 获取当前设备和绑定的房间
+		}
+
+		// 管理路由：按角色权限授权，权限定义见 models/role.go
 		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		admin.Use(middleware.AuthMiddleware())
 		{
-			admin.GET("/rooms", handlers.GetAllRooms) // 获取所有房间
+			perm := middleware.RequirePermission
+
+			admin.GET("/rooms", perm(models.PermRoomsRead), handlers.GetAllRooms)                                  // 获取所有房间
+			admin.GET("/reservations", perm(models.PermReservationsRead), handlers.GetAllReservations)             // 获取所有预订
+			admin.GET("/users", perm(models.PermUsersManage), handlers.GetUsers)                                   // 获取用户列表
+			admin.POST("/users", perm(models.PermUsersManage), handlers.CreateUser)                                // 创建用户（含员工账户）
+			admin.PUT("/users/:id/role", perm(models.PermUsersManage), handlers.ChangeUserRole)                    // 修改用户角色
+			admin.POST("/users/:id/disable", perm(models.PermUsersManage), handlers.DisableUser)                   // 停用账户
+			admin.POST("/users/:id/enable", perm(models.PermUsersManage), handlers.EnableUser)                     // 启用账户
+			admin.POST("/users/:id/reset-password", perm(models.PermUsersManage), handlers.ResetUserPassword)      // 重置密码
+			admin.POST("/users/:id/revoke-tokens", perm(models.PermUsersManage), handlers.RevokeUserTokens)        // 吊销用户的所有会话
+			admin.POST("/users/:id/unlock", perm(models.PermUsersManage), handlers.UnlockUser)                     // 解除用户的登录锁定
+			admin.GET("/login-locks", perm(models.PermUsersManage), handlers.GetLoginLocks)                        // 登录失败计数和锁定列表
+			admin.DELETE("/login-locks/:id", perm(models.PermUsersManage), handlers.DeleteLoginLock)               // 解除登录锁定（用户名或IP）
+			admin.GET("/login-failures", perm(models.PermUsersManage), handlers.GetLoginFailures)                  // 登录失败记录
+			admin.GET("/audit-logs", perm(models.PermAuditRead), handlers.GetAuditLogs)                            // 查询审计日志
+			admin.GET("/roles", perm(models.PermRolesManage), handlers.GetRoles)                                   // 获取角色和权限列表
+			admin.POST("/roles", perm(models.PermRolesManage), handlers.CreateRole)                                // 创建角色
+			admin.PUT("/roles/:name", perm(models.PermRolesManage), handlers.UpdateRole)                           // 修改角色权限
+			admin.DELETE("/roles/:name", perm(models.PermRolesManage), handlers.DeleteRole)                        // 删除角色
+			admin.POST("/folios/:bill_id/adjustments", perm(models.PermFoliosAdjust), handlers.AddFolioAdjustment) // 调账
+			admin.GET("/housekeeping", perm(models.PermRoomsHousekeeping), handlers.GetHousekeepingRooms)          // 客房部房态列表
+			admin.POST("/rooms/:room_id/clean", perm(models.PermRoomsHousekeeping), handlers.MarkRoomClean)        // 清扫完成
+			admin.PUT("/rooms/:room_id/state", perm(models.PermRoomsHousekeeping), handlers.UpdateRoomState)       // 修改房态
+			admin.GET("/devices", perm(models.PermDevicesManage), handlers.GetDevices)                             // 获取设备列表
+			admin.POST("/devices", perm(models.PermDevicesManage), handlers.RegisterDevice)                        // 登记设备并签发设备密钥
+			admin.POST("/devices/:id/revoke", perm(models.PermDevicesManage), handlers.RevokeDevice)               // 吊销设备密钥
 			// admin.GET("/airconditioners", handlers.GetAllAirConditioners) // 获取所有空调信息
-			admin.GET("/scheduler/status", handlers.GetSchedulerStatus) // 获取调度器状态
-			admin.PUT("/room-types/:id", handlers.UpdateRoomType)       // 修改指定ID的房间类型
-			admin.GET("/scheduler", handlers.GetAdminSchedulerStatus)
+			admin.GET("/scheduler/status", perm(models.PermSchedulerRead), handlers.GetSchedulerStatus) // 获取调度器状态
+			admin.PUT("/room-types/:id", perm(models.PermRoomTypesWrite), handlers.UpdateRoomType)      // 修改指定ID的房间类型
+			admin.GET("/scheduler", perm(models.PermSchedulerRead), handlers.GetAdminSchedulerStatus)
+			admin.GET("/scheduler/stream", perm(models.PermSchedulerRead), handlers.StreamAdminScheduler) // SSE实时推送调度器状态
 		}
 	}
 
@@ -113,7 +220,63 @@ func main() {
 	// log.Printf("  GET  /api/admin/airconditioners - 获取所有空调(管理员)")
 	// log.Printf("  GET  /api/admin/scheduler/status - 获取空调调度器状态(管理员)")
 
-	if err := r.Run(config.ServerPort); err != nil {
-		log.Fatal("服务器启动失败:", err)
+	srv := &http.Server{
+		Addr:    config.ServerPort,
+		Handler: r,
+	}
+	// WebSocket连接被劫持后不受Shutdown管理，SSE长连接会阻塞Shutdown，关闭时通过推送中心通知其断开
+	srv.RegisterOnShutdown(handlers.GetStatusHub().Close)
+	srv.RegisterOnShutdown(handlers.GetSchedulerFeed().Close)
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("服务器启动失败:", err)
+		}
+	}()
+
+	// 等待中断信号，优雅关闭服务器
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Printf("收到关闭信号，开始优雅关闭（超时时间 %v）", config.ShutdownTimeout)
+
+	shutdown(srv, config.ShutdownTimeout)
+}
+
+// loadThermalModel 创建房间热模型，牛顿冷却模型从房间类型表读取热参数
+func loadThermalModel(name string) (handlers.ThermalModel, error) {
+	if name == handlers.ThermalNewtonian {
+		return handlers.LoadNewtonianModel()
+	}
+	return handlers.NewThermalModel(name, nil)
+}
+
+// shutdown 依次停止接收HTTP请求并等待处理中的请求完成（同时断开WebSocket和SSE连接）、停止调度器并保存空调状态、关闭事件总线、关闭数据库
+func shutdown(srv *http.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("HTTP服务关闭超时: %v", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		handlers.GetScheduler().Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Println("等待空调调度器停止超时")
+	}
+
+	// 等待事件订阅者处理完已发布的事件
+	handlers.GetEventBus().Close()
+
+	if err := database.CloseDatabase(); err != nil {
+		log.Printf("关闭数据库失败: %v", err)
 	}
+	log.Println("服务器已关闭")
 }
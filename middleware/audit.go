@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+// 审计信息在上下文中的键，由处理函数通过 SetAuditEntity、SetAuditChange、SetAuditActor 设置
+const (
+	auditEntityKey = "audit_entity"
+	auditChangeKey = "audit_change"
+	auditActorKey  = "audit_actor"
+)
+
+// auditSummaryLimit 修改前后摘要的最大长度
+const auditSummaryLimit = 4000
+
+// auditRouteEntities 路由中参数前的路径段对应的实体类型，处理函数未设置目标实体时按路由推断
+var auditRouteEntities = map[string]string{
+	"users":          models.AuditEntityUser,
+	"roles":          models.AuditEntityRole,
+	"rooms":          models.AuditEntityRoom,
+	"airconditioner": models.AuditEntityRoom,
+	"room-types":     models.AuditEntityRoomType,
+	"reservations":   models.AuditEntityReservation,
+	"folios":         models.AuditEntityBill,
+	"devices":        models.AuditEntityDevice,
+	"login-locks":    models.AuditEntityLoginLock,
+}
+
+type auditEntity struct {
+	entityType string
+	entityID   string
+}
+
+type auditChange struct {
+	before interface{}
+	after  interface{}
+}
+
+type auditActor struct {
+	userID   int
+	username string
+	role     string
+}
+
+// SetAuditEntity 设置审计日志的目标实体，覆盖按路由推断的实体
+func SetAuditEntity(c *gin.Context, entityType string, entityID interface{}) {
+	c.Set(auditEntityKey, auditEntity{entityType: entityType, entityID: fmt.Sprint(entityID)})
+}
+
+// SetAuditChange 设置审计日志中修改前后的摘要，序列化为JSON保存，为nil表示不记录
+func SetAuditChange(c *gin.Context, before, after interface{}) {
+	c.Set(auditChangeKey, auditChange{before: before, after: after})
+}
+
+// SetAuditActor 设置审计日志的操作者，用于登录、注册等未经认证中间件的接口
+func SetAuditActor(c *gin.Context, userID int, username, role string) {
+	c.Set(auditActorKey, auditActor{userID: userID, username: username, role: role})
+}
+
+// AuditLogger 审计中间件，在处理完成后记录所有修改数据的请求（POST/PUT/PATCH/DELETE）
+// 需在认证中间件之前注册，这样被认证或权限检查拒绝的请求同样会被记录
+func AuditLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return // 未匹配任何路由
+		}
+
+		entry := models.AuditLog{
+			ActorID:   c.GetInt("user_id"),
+			ActorName: c.GetString("username"),
+			ActorRole: c.GetString("identity"),
+			DeviceID:  c.GetInt("device_id"),
+			Method:    c.Request.Method,
+			Route:     route,
+			Path:      c.Request.URL.Path,
+			Status:    c.Writer.Status(),
+			IP:        c.ClientIP(),
+		}
+		if value, ok := c.Get(auditActorKey); ok {
+			actor := value.(auditActor)
+			entry.ActorID, entry.ActorName, entry.ActorRole = actor.userID, actor.username, actor.role
+		}
+		if value, ok := c.Get(auditEntityKey); ok {
+			entity := value.(auditEntity)
+			entry.EntityType, entry.EntityID = entity.entityType, entity.entityID
+		} else {
+			entry.EntityType, entry.EntityID = routeEntity(c, route)
+		}
+		if value, ok := c.Get(auditChangeKey); ok {
+			change := value.(auditChange)
+			entry.Before = auditSummary(change.before)
+			entry.After = auditSummary(change.after)
+		}
+
+		if err := database.DB.Create(&entry).Error; err != nil {
+			log.Printf("记录审计日志失败: %s %s: %v", entry.Method, entry.Path, err)
+		}
+	}
+}
+
+// routeEntity 按路由中最后一个参数推断目标实体，如 /rooms/:room_id/checkout 推断为房间
+func routeEntity(c *gin.Context, route string) (string, string) {
+	segments := strings.Split(route, "/")
+	for i := len(segments) - 1; i > 0; i-- {
+		if !strings.HasPrefix(segments[i], ":") {
+			continue
+		}
+		resource := segments[i-1]
+		entityType, ok := auditRouteEntities[resource]
+		if !ok {
+			entityType = resource
+		}
+		return entityType, c.Param(strings.TrimPrefix(segments[i], ":"))
+	}
+	return "", ""
+}
+
+// auditSummary 将修改前后的摘要序列化为JSON，超出长度时截断
+func auditSummary(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("序列化审计摘要失败: %v", err)
+		return ""
+	}
+	if len(data) > auditSummaryLimit {
+		return strings.ToValidUTF8(string(data[:auditSummaryLimit]), "")
+	}
+	return string(data)
+}
@@ -1,51 +1,81 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
 )
 
 var jwtSecret []byte
 
-// InitJWT 初始化JWT密钥
-func InitJWT(secret string) {
+// accessTokenTTL 访问令牌的有效期，过期后使用刷新令牌换取新的访问令牌
+var accessTokenTTL = 15 * time.Minute
+
+// InitJWT 初始化JWT密钥和访问令牌有效期
+func InitJWT(secret string, ttl time.Duration) {
 	jwtSecret = []byte(secret)
+	if ttl > 0 {
+		accessTokenTTL = ttl
+	}
 }
 
-// Claims JWT声明结构
+// AccessTokenTTL 访问令牌的有效期
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
+// Claims JWT声明结构，jti（RegisteredClaims.ID）用于吊销单个访问令牌
 type Claims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	Identity string `json:"identity"`
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Identity  string `json:"identity"`
+	SessionID string `json:"sid"` // 登录会话ID，退出登录时吊销整个会话
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID int, username, identity string) (string, error) {
-	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Identity: identity,
+// GenerateToken 生成JWT访问令牌，返回令牌和声明（含jti和过期时间）
+func GenerateToken(userID int, username, identity, sessionID string) (string, *Claims, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		UserID:    userID,
+		Username:  username,
+		Identity:  identity,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24小时过期
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "bupt-hotel",
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	signed, err := token.SignedString(jwtSecret)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
 // ParseToken 解析JWT token
 func ParseToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
@@ -58,56 +88,138 @@ func ParseToken(tokenString string) (*Claims, error) {
 	return nil, jwt.ErrInvalidKey
 }
 
-// AuthMiddleware JWT认证中间件
+// AuthMiddleware JWT认证中间件，只接受用户令牌
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "缺少Authorization头",
-			})
-			c.Abort()
-			return
-		}
+		authenticate(c, false)
+	}
+}
 
-		// Bearer token格式
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "无效的Authorization格式",
-			})
-			c.Abort()
+// RoomAuthMiddleware 空调接口的认证中间件，同时接受用户令牌、设备密钥和它们换取的连接票据
+// 设备绑定的房间存入上下文的 panel_room_id，由 RequireRoomAccess 检查
+func RoomAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(DeviceKeyHeader) != "" {
+			authenticateDevice(c)
 			return
 		}
+		authenticate(c, true)
+	}
+}
 
-		claims, err := ParseToken(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "无效的token",
+// authenticate 校验请求中的JWT并将用户信息存入上下文，allowDevice为false时拒绝设备换取的连接票据
+func authenticate(c *gin.Context, allowDevice bool) {
+	claims, deviceID, ok := requestCredentials(c)
+	if !ok {
+		return
+	}
+	if deviceID != 0 {
+		if !allowDevice {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "设备只能访问空调接口",
 			})
 			c.Abort()
 			return
 		}
+		authenticateDeviceID(c, deviceID)
+		return
+	}
 
-		// 将用户信息存储到上下文中
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("identity", claims.Identity)
-		c.Next()
+	revoked, err := tokenRevoked(claims)
+	if err != nil {
+		log.Printf("检查token吊销状态失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "验证token失败",
+		})
+		c.Abort()
+		return
+	}
+	if revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "token已失效，请重新登录",
+		})
+		c.Abort()
+		return
 	}
+
+	// 将用户信息存储到上下文中
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("identity", claims.Identity)
+	c.Set("claims", claims)
+	c.Next()
 }
 
-// AdminMiddleware 管理员权限中间件
-func AdminMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		identity, exists := c.Get("identity")
-		if !exists || identity != "administrator" {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "需要管理员权限",
-			})
-			c.Abort()
-			return
+// requestCredentials 从Authorization头或连接票据中获取令牌声明，设备换取的连接票据返回设备ID，失败时返回401并中止请求
+// 浏览器WebSocket和EventSource无法设置请求头，可以通过ticket查询参数传递一次性连接票据
+func requestCredentials(c *gin.Context) (*Claims, int, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" && isStreamingRequest(c) {
+		if ticket := c.Query(StreamTicketQuery); ticket != "" {
+			t, ok := redeemStreamTicket(ticket)
+			if !ok {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "连接票据无效或已使用",
+				})
+				c.Abort()
+				return nil, 0, false
+			}
+			return t.claims, t.deviceID, true
 		}
-		c.Next()
 	}
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "缺少Authorization头",
+		})
+		c.Abort()
+		return nil, 0, false
+	}
+
+	// Bearer token格式
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenString == authHeader {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "无效的Authorization格式",
+		})
+		c.Abort()
+		return nil, 0, false
+	}
+
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "无效的token",
+		})
+		c.Abort()
+		return nil, 0, false
+	}
+	return claims, 0, true
+}
+
+// tokenRevoked 检查访问令牌的jti是否在吊销列表中
+// 没有jti的令牌（旧版本签发的24小时令牌）无法吊销，没有会话ID的令牌（已停用的房间面板令牌）不属于任何登录，均视为已失效
+func tokenRevoked(claims *Claims) (bool, error) {
+	if claims.ID == "" || claims.SessionID == "" {
+		return true, nil
+	}
+	var count int64
+	if err := database.DB.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// newTokenID 生成随机的令牌ID
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isStreamingRequest 判断请求是否为WebSocket握手或SSE订阅请求
+func isStreamingRequest(c *gin.Context) bool {
+	return strings.EqualFold(c.GetHeader("Upgrade"), "websocket") ||
+		strings.Contains(c.GetHeader("Accept"), "text/event-stream")
 }
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRoomAuthRejectsPanelTokens(t *testing.T) {
+	r, _ := newDeviceTestRouter(t, "dk_0123456789abcdef")
+	InitJWT("test-secret", 0)
+
+	// 已停用的房间面板令牌：有jti但不属于任何登录会话
+	now := time.Now()
+	panel, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		Username: "room-panel-101",
+		Identity: "room_panel",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "panel-jti",
+			ExpiresAt: jwt.NewNumericDate(now.AddDate(1, 0, 0)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "bupt-hotel",
+		},
+	}).SignedString(jwtSecret)
+	if err != nil {
+		t.Fatalf("签发面板令牌失败: %v", err)
+	}
+	user, _, err := GenerateToken(1, "guest", "customer", "session")
+	if err != nil {
+		t.Fatalf("签发用户令牌失败: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"面板令牌", panel, http.StatusUnauthorized},
+		{"用户令牌", user, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s返回 %d，期望 %d: %s", tc.name, w.Code, tc.want, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+// DeviceKeyHeader 设备密钥请求头
+const DeviceKeyHeader = "X-Device-Key"
+
+// IdentityDevice 使用设备密钥认证的房间设备的身份
+const IdentityDevice = "device"
+
+// deviceSeenInterval 最近使用时间的更新间隔，避免长轮询每次请求都写数据库
+const deviceSeenInterval = time.Minute
+
+// HashDeviceKey 计算设备密钥的SHA-256哈希，数据库中只保存哈希
+func HashDeviceKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeviceAuthMiddleware 设备密钥认证中间件，只接受 X-Device-Key 请求头中的设备密钥
+func DeviceAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticateDevice(c)
+	}
+}
+
+// authenticateDevice 校验 X-Device-Key 请求头中的设备密钥，将设备和绑定空调所在的房间存入上下文
+// 设备密钥长期有效，不接受查询参数传递；浏览器WebSocket需要先换取一次性连接票据
+func authenticateDevice(c *gin.Context) {
+	key := c.GetHeader(DeviceKeyHeader)
+	if key == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "缺少" + DeviceKeyHeader + "头",
+		})
+		c.Abort()
+		return
+	}
+
+	var device models.Device
+	if err := database.DB.Where("key_hash = ?", HashDeviceKey(key)).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "无效的设备密钥",
+			})
+			c.Abort()
+			return
+		}
+		log.Printf("查询设备失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "验证设备密钥失败",
+		})
+		c.Abort()
+		return
+	}
+	authorizeDevice(c, device)
+}
+
+// authenticateDeviceID 使用设备换取的连接票据认证，重新加载设备以检查票据签发后是否被吊销
+func authenticateDeviceID(c *gin.Context, deviceID int) {
+	var device models.Device
+	if err := database.DB.First(&device, deviceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "设备不存在",
+			})
+			c.Abort()
+			return
+		}
+		log.Printf("查询设备 %d 失败: %v", deviceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "验证连接票据失败",
+		})
+		c.Abort()
+		return
+	}
+	authorizeDevice(c, device)
+}
+
+// authorizeDevice 检查设备未被吊销，将设备和绑定空调所在的房间存入上下文
+// 绑定的房间存入 panel_room_id，由 RequireRoomAccess 检查
+func authorizeDevice(c *gin.Context, device models.Device) {
+	if device.Revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "设备密钥已吊销",
+		})
+		c.Abort()
+		return
+	}
+
+	var ac models.AirConditioner
+	if err := database.DB.First(&ac, device.AcID).Error; err != nil {
+		log.Printf("加载设备 %d 绑定的空调 %d 失败: %v", device.ID, device.AcID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "加载设备绑定的空调失败",
+		})
+		c.Abort()
+		return
+	}
+
+	touchDevice(device, c.ClientIP())
+
+	c.Set("device_id", device.ID)
+	c.Set("panel_room_id", ac.RoomID)
+	c.Set("username", device.Name)
+	c.Set("identity", IdentityDevice)
+	c.Next()
+}
+
+// touchDevice 记录设备最近一次使用密钥的时间和IP，间隔不足 deviceSeenInterval 时不更新
+func touchDevice(device models.Device, ip string) {
+	now := time.Now()
+	if device.LastSeenAt != nil && now.Sub(*device.LastSeenAt) < deviceSeenInterval && device.LastSeenIP == ip {
+		return
+	}
+	if err := database.DB.Model(&models.Device{}).
+		Where("id = ?", device.ID).
+		Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"last_seen_ip": ip,
+		}).Error; err != nil {
+		log.Printf("更新设备 %d 的最近使用时间失败: %v", device.ID, err)
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+// newDeviceTestRouter 初始化临时数据库，登记一台绑定空调101的设备，返回使用 RoomAuthMiddleware 的路由和设备
+func newDeviceTestRouter(t *testing.T, key string) (*gin.Engine, models.Device) {
+	t.Helper()
+
+	if err := database.InitDatabase(filepath.Join(t.TempDir(), "hotel.db")); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		database.CloseDatabase()
+	})
+
+	device := models.Device{Name: "101房间墙面面板", AcID: 101, KeyHash: HashDeviceKey(key), KeyPrefix: key[:4]}
+	if err := database.DB.Create(&device).Error; err != nil {
+		t.Fatalf("登记设备失败: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", RoomAuthMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"room_id": c.GetInt("panel_room_id")})
+	})
+	return r, device
+}
+
+// websocketRequest 发起带WebSocket握手请求头的请求，返回状态码
+func websocketRequest(r *gin.Engine, target string, header http.Header) int {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("Upgrade", "websocket")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestDeviceKeyQueryRejected(t *testing.T) {
+	const key = "dk_0123456789abcdef"
+	r, _ := newDeviceTestRouter(t, key)
+
+	if code := websocketRequest(r, "/ws?device_key="+key, nil); code != http.StatusUnauthorized {
+		t.Fatalf("查询参数中的设备密钥返回 %d，期望 401", code)
+	}
+	if code := websocketRequest(r, "/ws", http.Header{DeviceKeyHeader: {key}}); code != http.StatusOK {
+		t.Fatalf("请求头中的设备密钥返回 %d，期望 200", code)
+	}
+}
+
+func TestDeviceStreamTicket(t *testing.T) {
+	r, device := newDeviceTestRouter(t, "dk_0123456789abcdef")
+
+	ticket, _, err := IssueDeviceStreamTicket(device.ID)
+	if err != nil {
+		t.Fatalf("签发连接票据失败: %v", err)
+	}
+	if code := websocketRequest(r, "/ws?ticket="+ticket, nil); code != http.StatusOK {
+		t.Fatalf("使用设备连接票据返回 %d，期望 200", code)
+	}
+	if code := websocketRequest(r, "/ws?ticket="+ticket, nil); code != http.StatusUnauthorized {
+		t.Fatalf("重复使用连接票据返回 %d，期望 401", code)
+	}
+
+	// 票据签发后设备被吊销，票据随之失效
+	ticket, _, err = IssueDeviceStreamTicket(device.ID)
+	if err != nil {
+		t.Fatalf("签发连接票据失败: %v", err)
+	}
+	database.DB.Model(&models.Device{}).Where("id = ?", device.ID).Update("revoked", true)
+	if code := websocketRequest(r, "/ws?ticket="+ticket, nil); code != http.StatusUnauthorized {
+		t.Fatalf("已吊销设备的连接票据返回 %d，期望 401", code)
+	}
+}
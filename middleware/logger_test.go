@@ -0,0 +1,23 @@
+package middleware
+
+import "testing"
+
+func TestRedactPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/api/admin/scheduler/stream", want: "/api/admin/scheduler/stream"},
+		{path: "/api/admin/audit-logs?page=2", want: "/api/admin/audit-logs?page=2"},
+		{path: "/api/auth/airconditioner/101/ws?token=eyJhbGciOi.x.y", want: "/api/auth/airconditioner/101/ws?token=REDACTED"},
+		{path: "/api/auth/airconditioner/101/ws?device_key=secret&x=1", want: "/api/auth/airconditioner/101/ws?device_key=REDACTED&x=1"},
+		{path: "/api/admin/scheduler/stream?ticket=abc", want: "/api/admin/scheduler/stream?ticket=REDACTED"},
+		{path: "/api/admin/scheduler/stream?token=%zz", want: "/api/admin/scheduler/stream?REDACTED"},
+	}
+
+	for _, tt := range tests {
+		if got := redactPath(tt.path); got != tt.want {
+			t.Errorf("redactPath(%q) = %q，期望 %q", tt.path, got, tt.want)
+		}
+	}
+}
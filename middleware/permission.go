@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+// RequirePermission 权限中间件：当前用户的角色需要拥有指定权限，需在AuthMiddleware之后使用
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := CurrentRole(c)
+		if err != nil {
+			log.Printf("加载角色权限失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "加载权限失败",
+			})
+			c.Abort()
+			return
+		}
+		if !role.HasPermission(permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "缺少权限: " + permission,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasPermission 当前用户的角色是否拥有指定权限，加载失败时视为没有权限
+func HasPermission(c *gin.Context, permission string) bool {
+	role, err := CurrentRole(c)
+	if err != nil {
+		log.Printf("加载角色权限失败: %v", err)
+		return false
+	}
+	return role.HasPermission(permission)
+}
+
+// CurrentRole 加载当前用户的角色，同一请求内只查询一次；角色不存在时返回没有任何权限的空角色
+func CurrentRole(c *gin.Context) (models.Role, error) {
+	if value, ok := c.Get("role"); ok {
+		return value.(models.Role), nil
+	}
+
+	identity := c.GetString("identity")
+	var role models.Role
+	if err := database.DB.Where("name = ?", identity).First(&role).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return role, err
+		}
+		role = models.Role{Name: identity}
+	}
+	c.Set("role", role)
+	return role, nil
+}
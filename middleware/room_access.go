@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bupt-hotel/database"
+	"bupt-hotel/models"
+)
+
+// RequireRoomAccess 空调接口的房间访问控制，需在 AuthMiddleware 或 RoomAuthMiddleware 之后使用
+// 房间面板设备只能访问绑定的房间；用户需要是该房间当前的入住客人，或拥有 scheduler:override 权限
+func RequireRoomAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, err := strconv.Atoi(c.Param("room_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "房间ID无效",
+			})
+			c.Abort()
+			return
+		}
+
+		if panelRoomID, ok := c.Get("panel_room_id"); ok {
+			if panelRoomID != roomID {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "房间面板只能访问绑定的房间",
+				})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if HasPermission(c, models.PermSchedulerOverride) {
+			c.Next()
+			return
+		}
+
+		var count int64
+		if err := database.DB.Model(&models.RoomInfo{}).
+			Where("room_id = ? AND state = ? AND client_id = ?", roomID, models.RoomOccupied, strconv.Itoa(c.GetInt("user_id"))).
+			Count(&count).Error; err != nil {
+			log.Printf("检查房间 %d 的入住客人失败: %v", roomID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "检查房间权限失败",
+			})
+			c.Abort()
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "只有该房间当前的入住客人可以操作空调",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// StreamTicketTTL 连接票据的有效期，票据只能使用一次
+const StreamTicketTTL = 30 * time.Second
+
+// StreamTicketQuery WebSocket和SSE请求中携带连接票据的查询参数
+const StreamTicketQuery = "ticket"
+
+// streamTicket 连接票据代表的认证信息：用户令牌的声明或者设备ID
+type streamTicket struct {
+	claims    *Claims
+	deviceID  int
+	expiresAt time.Time
+}
+
+var (
+	streamTicketsMu sync.Mutex
+	streamTickets   = make(map[string]streamTicket) // 票据 -> 认证信息
+)
+
+// IssueStreamTicket 为已认证的令牌签发一次性连接票据
+// 浏览器WebSocket和EventSource无法设置请求头，通过查询参数传递票据代替长期有效的令牌，
+// 票据被访问日志记录也无法再次使用
+func IssueStreamTicket(claims *Claims) (string, time.Time, error) {
+	return issueStreamTicket(streamTicket{claims: claims})
+}
+
+// IssueDeviceStreamTicket 为已认证的设备签发一次性连接票据，代替长期有效的设备密钥
+func IssueDeviceStreamTicket(deviceID int) (string, time.Time, error) {
+	return issueStreamTicket(streamTicket{deviceID: deviceID})
+}
+
+// issueStreamTicket 生成票据并保存，顺带清理过期未使用的票据
+func issueStreamTicket(t streamTicket) (string, time.Time, error) {
+	ticket, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	t.expiresAt = now.Add(StreamTicketTTL)
+
+	streamTicketsMu.Lock()
+	defer streamTicketsMu.Unlock()
+
+	for key, existing := range streamTickets {
+		if now.After(existing.expiresAt) {
+			delete(streamTickets, key)
+		}
+	}
+	streamTickets[ticket] = t
+	return ticket, t.expiresAt, nil
+}
+
+// redeemStreamTicket 使用连接票据，票据无论是否过期都会被删除
+func redeemStreamTicket(ticket string) (streamTicket, bool) {
+	streamTicketsMu.Lock()
+	defer streamTicketsMu.Unlock()
+
+	t, ok := streamTickets[ticket]
+	if !ok {
+		return streamTicket{}, false
+	}
+	delete(streamTickets, ticket)
+	if time.Now().After(t.expiresAt) {
+		return streamTicket{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamTicketSingleUse(t *testing.T) {
+	claims := &Claims{UserID: 1, Username: "admin"}
+	ticket, expiresAt, err := IssueStreamTicket(claims)
+	if err != nil {
+		t.Fatalf("签发连接票据失败: %v", err)
+	}
+	if ttl := time.Until(expiresAt); ttl <= 0 || ttl > StreamTicketTTL {
+		t.Fatalf("票据有效期为 %v，期望不超过 %v", ttl, StreamTicketTTL)
+	}
+
+	got, ok := redeemStreamTicket(ticket)
+	if !ok || got.claims != claims {
+		t.Fatalf("第一次使用票据失败: %+v, %v", got, ok)
+	}
+	if _, ok := redeemStreamTicket(ticket); ok {
+		t.Fatal("票据不能重复使用")
+	}
+}
+
+func TestStreamTicketExpired(t *testing.T) {
+	ticket, _, err := IssueStreamTicket(&Claims{UserID: 1})
+	if err != nil {
+		t.Fatalf("签发连接票据失败: %v", err)
+	}
+
+	streamTicketsMu.Lock()
+	entry := streamTickets[ticket]
+	entry.expiresAt = time.Now().Add(-time.Second)
+	streamTickets[ticket] = entry
+	streamTicketsMu.Unlock()
+
+	if _, ok := redeemStreamTicket(ticket); ok {
+		t.Fatal("过期的票据不能使用")
+	}
+}
@@ -17,7 +17,7 @@ type AirConditionerOperation struct {
 	AcID   int `gorm:"type:int;index"` // 关联空调ID
 
 	// 空调操作状态：0-开机 1-关机 2-调温
-	OperationState int `gorm:"type:int;default:1"` // 0: 开机 1: 关机 2: 调温
+	OperationState int `gorm:"type:int"` // 0: 开机 1: 关机 2: 调温，不设默认值，否则开机的0会被保存为默认值
 
 	// 风速：high-高速 medium-中速 low-低速
 	Speed string `gorm:"type:varchar(20);default:'medium'"` // high/medium/low
@@ -71,8 +71,9 @@ type AirConditionerDetail struct {
 	TotalCost   float32 `gorm:"type:float(10,2)"` // 总花费金额
 
 	// 费率和变化信息
-	Rate       float32 `gorm:"type:float(5,2)"` // 每分钟费率(元/分钟)
-	TempChange int     `gorm:"type:int"`        // 温度变化*10
+	Rate       float32 `gorm:"type:float(5,2)"`            // 每分钟费率(元/分钟)
+	Charge     float32 `gorm:"type:float(10,4);default:0"` // 本tick的计费金额，账单空调费用为其合计
+	TempChange int     `gorm:"type:int"`                   // 温度变化*10
 
 	// 记录时间
 	CreatedAt time.Time `gorm:"autoCreateTime"`
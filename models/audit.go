@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// 审计日志的目标实体类型
+const (
+	AuditEntityUser        = "user"
+	AuditEntityRole        = "role"
+	AuditEntityRoom        = "room"
+	AuditEntityRoomType    = "room_type"
+	AuditEntityReservation = "reservation"
+	AuditEntityBill        = "bill"
+	AuditEntityDevice      = "device"
+	AuditEntityLoginLock   = "login_lock"
+)
+
+// 审计日志表
+// 记录所有修改数据的接口调用（POST/PUT/PATCH/DELETE），包括被拒绝和失败的请求
+type AuditLog struct {
+	ID         int       `gorm:"primaryKey;autoIncrement"`
+	ActorID    int       `gorm:"type:int;index"`         // 操作用户ID，未登录和设备为0
+	ActorName  string    `gorm:"type:varchar(100)"`      // 用户名、设备名称或登录时使用的用户名
+	ActorRole  string    `gorm:"type:varchar(50)"`       // 操作时的角色，设备为device
+	DeviceID   int       `gorm:"type:int"`               // 使用设备密钥调用时的设备ID
+	Method     string    `gorm:"type:varchar(8)"`        // HTTP方法
+	Route      string    `gorm:"type:varchar(200)"`      // 路由模板，如 /api/auth/rooms/:room_id/checkout
+	Path       string    `gorm:"type:varchar(200)"`      // 实际请求路径
+	EntityType string    `gorm:"type:varchar(32);index"` // 目标实体类型
+	EntityID   string    `gorm:"type:varchar(64);index"` // 目标实体ID
+	Status     int       `gorm:"type:int"`               // 响应状态码
+	Before     string    `gorm:"type:text"`              // 修改前的摘要（JSON）
+	After      string    `gorm:"type:text"`              // 修改后的摘要（JSON）
+	IP         string    `gorm:"type:varchar(64)"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// 房间设备表（房间内的空调控制面板等）
+// 每台设备绑定一台空调，使用设备密钥调用该空调所在房间的空调接口；只保存密钥的SHA-256哈希
+type Device struct {
+	ID         int        `gorm:"primaryKey;autoIncrement"`
+	Name       string     `gorm:"type:varchar(100)"`            // 设备名称，如“101房间墙面面板”
+	AcID       int        `gorm:"type:int;index"`               // 绑定的空调ID
+	KeyHash    string     `gorm:"type:varchar(64);uniqueIndex"` // 设备密钥的SHA-256哈希
+	KeyPrefix  string     `gorm:"type:varchar(16)"`             // 设备密钥的前几位，用于辨认密钥
+	Revoked    bool       `gorm:"default:false;index"`          // 密钥是否已吊销
+	RevokedAt  *time.Time `gorm:"type:datetime"`
+	LastSeenAt *time.Time `gorm:"type:datetime"` // 最近一次使用密钥的时间
+	LastSeenIP string     `gorm:"type:varchar(64)"`
+	CreatedBy  int        `gorm:"type:int"` // 登记设备的用户ID
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime"`
+}
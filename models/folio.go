@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// 账单明细类型
+const (
+	FolioRoomCharge = "room"       // 房费
+	FolioACCharge   = "ac"         // 空调费
+	FolioDeposit    = "deposit"    // 押金
+	FolioAdjustment = "adjustment" // 调账
+	FolioSettlement = "settlement" // 结账（收款或退还余额）
+)
+
+// 账单明细表
+// 每次入住的账单号对应一组明细，金额为正表示应收费用，为负表示已收款项（押金、结账收款）或减免
+type FolioEntry struct {
+	ID          int       `gorm:"primaryKey"`
+	BillID      int       `gorm:"type:int;index"` // 账单号，与房间操作表保持一致
+	RoomID      int       `gorm:"type:int;index"`
+	EntryType   string    `gorm:"type:varchar(20);index"` // room/ac/deposit/adjustment/settlement
+	Description string    `gorm:"type:varchar(255)"`
+	Quantity    float64   `gorm:"type:float(10,2)"`  // 数量，如房费的晚数
+	UnitPrice   float64   `gorm:"type:float(10,2)"`  // 单价
+	Amount      float64   `gorm:"type:float(10,2)"`  // 金额
+	Operator    string    `gorm:"type:varchar(255)"` // 操作人，系统自动记账为 system
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
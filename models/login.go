@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// 登录失败计数的对象
+const (
+	LoginThrottleUsername = "username" // 按用户名计数
+	LoginThrottleIP       = "ip"       // 按客户端IP计数
+)
+
+// 登录失败原因
+const (
+	LoginFailureUnknownUser = "unknown_user" // 用户不存在
+	LoginFailureBadPassword = "bad_password" // 密码错误
+	LoginFailureDisabled    = "disabled"     // 账户已停用
+)
+
+// 登录失败计数表
+// 每个用户名和每个IP各一条记录，连续失败超过阈值后按指数退避锁定，登录成功后清除用户名的计数
+// 超过计数窗口没有再失败的记录在记录下一次登录失败时删除
+type LoginThrottle struct {
+	ID            int        `gorm:"primaryKey;autoIncrement"`
+	Kind          string     `gorm:"type:varchar(16);uniqueIndex:idx_login_throttle_key"`  // username 或 ip
+	Key           string     `gorm:"type:varchar(100);uniqueIndex:idx_login_throttle_key"` // 用户名或IP
+	Failures      int        `gorm:"type:int"`                                             // 计数窗口内的连续失败次数
+	LastFailureAt time.Time  `gorm:"type:datetime;index"`
+	LockedUntil   *time.Time `gorm:"type:datetime;index"` // 锁定截止时间，为空表示未锁定
+	LockedRejects int        `gorm:"type:int;default:0"`  // 计数窗口内锁定期间被拒绝的登录次数，这些尝试不写入登录失败记录
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime"`
+}
+
+// 登录失败记录表
+type LoginFailure struct {
+	ID        int       `gorm:"primaryKey;autoIncrement"`
+	Username  string    `gorm:"type:varchar(100);index"`
+	IP        string    `gorm:"type:varchar(64);index"`
+	Reason    string    `gorm:"type:varchar(32)"` // unknown_user/bad_password/disabled
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
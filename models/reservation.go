@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// 预订状态
+const (
+	ReservationBooked    = 0 // 已预订
+	ReservationCheckedIn = 1 // 已入住
+	ReservationCancelled = 2 // 已取消
+)
+
+// 房间预订表
+// 预订的日期范围为[StartDate, EndDate)，即到店日期到离店日期（不含离店当天）
+type Reservation struct {
+	ID         int       `gorm:"primaryKey;autoIncrement"`
+	ClientID   string    `gorm:"type:varchar(255);index"`
+	ClientName string    `gorm:"type:varchar(255)"`
+	RoomTypeID int       `gorm:"type:int;index"` // 预订的房间类型
+	RoomID     int       `gorm:"type:int;index"` // 预订的房间，为0表示只预订房间类型，入住时分配房间
+	StartDate  time.Time `gorm:"type:datetime;index"`
+	EndDate    time.Time `gorm:"type:datetime;index"`
+	State      int       `gorm:"type:int;index"` // 0: 已预订 1: 已入住 2: 已取消
+	BillID     int       `gorm:"type:int"`       // 入住后的账单号
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 权限字符串，格式为“资源:操作”
+// 客户对自己的房间、预订和账单的操作不需要权限，权限用于访问他人的数据和管理功能
+const (
+	PermissionAll = "*" // 全部权限
+
+	PermRoomsRead          = "rooms:read"          // 查看所有房间
+	PermRoomsCheckout      = "rooms:checkout"      // 为任意客人办理退房
+	PermRoomsModify        = "rooms:modify"        // 为任意客人续住、提前离店和换房
+	PermRoomsHousekeeping  = "rooms:housekeeping"  // 查看和修改客房部房态
+	PermRoomTypesWrite     = "room_types:write"    // 修改房间类型
+	PermReservationsRead   = "reservations:read"   // 查看所有预订
+	PermReservationsManage = "reservations:manage" // 取消任意预订、为任意预订办理入住
+	PermReportsRead        = "reports:read"        // 查看任意账单，下载账单和空调使用详单
+	PermFoliosAdjust       = "folios:adjust"       // 调账
+	PermSchedulerRead      = "scheduler:read"      // 查看调度器状态
+	PermSchedulerOverride  = "scheduler:override"  // 控制任意房间的空调
+	PermDevicesManage      = "devices:manage"      // 管理房间设备和设备密钥
+	PermUsersManage        = "users:manage"        // 用户管理、吊销会话和解除登录锁定
+	PermRolesManage        = "roles:manage"        // 角色和权限管理
+	PermAuditRead          = "audit:read"          // 查询审计日志
+)
+
+// PermissionDescriptions 所有权限及其说明
+var PermissionDescriptions = map[string]string{
+	PermRoomsRead:          "查看所有房间",
+	PermRoomsCheckout:      "为任意客人办理退房",
+	PermRoomsModify:        "为任意客人续住、提前离店和换房",
+	PermRoomsHousekeeping:  "查看和修改客房部房态",
+	PermRoomTypesWrite:     "修改房间类型",
+	PermReservationsRead:   "查看所有预订",
+	PermReservationsManage: "取消任意预订、为任意预订办理入住",
+	PermReportsRead:        "查看任意账单，下载账单和空调使用详单",
+	PermFoliosAdjust:       "调账",
+	PermSchedulerRead:      "查看调度器状态",
+	PermSchedulerOverride:  "控制任意房间的空调",
+	PermDevicesManage:      "管理房间设备和设备密钥",
+	PermUsersManage:        "用户管理、吊销会话和解除登录锁定",
+	PermRolesManage:        "角色和权限管理",
+	PermAuditRead:          "查询审计日志",
+}
+
+// 内置角色，不能删除
+const (
+	RoleCustomer      = "customer"
+	RoleAdministrator = "administrator"
+)
+
+// 角色表
+// 用户的Identity为角色名称，Permissions为权限字符串列表，支持“*”和“资源:*”通配符
+type Role struct {
+	ID          int            `gorm:"primaryKey;autoIncrement"`
+	Name        string         `gorm:"type:varchar(50);not null;unique"` // 角色名称
+	Description string         `gorm:"type:varchar(255)"`
+	Permissions pq.StringArray `gorm:"type:text[]"` // 权限列表
+	CreatedAt   time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime"`
+}
+
+// HasPermission 角色是否拥有某项权限
+func (r Role) HasPermission(permission string) bool {
+	for _, granted := range r.Permissions {
+		if granted == PermissionAll || granted == permission {
+			return true
+		}
+		if strings.HasSuffix(granted, ":*") && strings.HasPrefix(permission, strings.TrimSuffix(granted, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPermission 权限字符串是否有效：已定义的权限、“*”或已定义资源的“资源:*”
+func ValidPermission(permission string) bool {
+	if permission == PermissionAll {
+		return true
+	}
+	if _, ok := PermissionDescriptions[permission]; ok {
+		return true
+	}
+	if strings.HasSuffix(permission, ":*") {
+		prefix := strings.TrimSuffix(permission, "*")
+		for defined := range PermissionDescriptions {
+			if strings.HasPrefix(defined, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetDefaultRoles 返回默认的角色数据
+func GetDefaultRoles() []Role {
+	return []Role{
+		{
+			Name:        RoleCustomer,
+			Description: "客户，只能操作自己的房间、预订和账单",
+			Permissions: pq.StringArray{},
+		},
+		{
+			Name:        RoleAdministrator,
+			Description: "系统管理员，拥有全部权限",
+			Permissions: pq.StringArray{PermissionAll},
+		},
+		{
+			Name:        "front_desk",
+			Description: "前台，办理入住、退房、续住、换房和预订",
+			Permissions: pq.StringArray{
+				PermRoomsRead, PermRoomsCheckout, PermRoomsModify, PermRoomsHousekeeping,
+				PermReservationsRead, PermReservationsManage, PermReportsRead,
+			},
+		},
+		{
+			Name:        "ac_operator",
+			Description: "空调操作员，监控调度器、控制各房间空调并管理房间设备",
+			Permissions: pq.StringArray{PermRoomsRead, PermSchedulerRead, PermSchedulerOverride, PermDevicesManage},
+		},
+		{
+			Name:        "manager",
+			Description: "经理，负责前台、客房、账务和房间类型",
+			Permissions: pq.StringArray{
+				"rooms:*", PermRoomTypesWrite, "reservations:*", PermReportsRead,
+				PermFoliosAdjust, PermSchedulerRead, PermUsersManage,
+			},
+		},
+		{
+			Name:        "auditor",
+			Description: "审计，只读查看房间、预订、账单、调度器和审计日志",
+			Permissions: pq.StringArray{PermRoomsRead, PermReservationsRead, PermReportsRead, PermSchedulerRead, PermAuditRead},
+		},
+	}
+}
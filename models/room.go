@@ -13,10 +13,21 @@ type RoomType struct {
 	Description string         `gorm:"type:text"`                        // 房间描述
 	PriceRange  string         `gorm:"type:varchar(100)"`                // 价格范围
 	Features    pq.StringArray `gorm:"type:text[]"`                      // 房间特色功能列表
+	Insulation  float64        `gorm:"default:0.001"`                    // 散热系数，每tick向环境温度靠拢的温差比例
+	ThermalMass float64        `gorm:"default:1"`                        // 热容倍数，标准房间为1
 	CreatedAt   time.Time      `gorm:"autoCreateTime"`
 	UpdatedAt   time.Time      `gorm:"autoUpdateTime"`
 }
 
+// 房间状态
+const (
+	RoomVacantClean  = 0 // 空房已清扫，可出售
+	RoomOccupied     = 1 // 已入住
+	RoomVacantDirty  = 2 // 空房待清扫，退房或换房后进入
+	RoomOutOfOrder   = 3 // 维修停用，不计入可预订房间数
+	RoomOutOfService = 4 // 暂停使用（如小修、保留），计入可预订房间数但暂不可入住
+)
+
 // 房间信息表
 type RoomInfo struct {
 	RoomID       int       `gorm:"primaryKey"`
@@ -26,9 +37,11 @@ type RoomInfo struct {
 	ClientName   string    `gorm:"type:varchar(255)"`
 	CheckinTime  time.Time `gorm:"type:datetime"`
 	CheckoutTime time.Time `gorm:"type:datetime"`
-	State        int       // 0: 空房 1: 已入住
-	DailyRate    float32   `gorm:"type:float(7,2)"`  // 每日房费
-	Deposit      float32   `gorm:"type:float(10,2)"` // 押金
+	State        int       // 0: 空房已清扫 1: 已入住 2: 空房待清扫 3: 维修停用 4: 暂停使用
+	StateReason  string    `gorm:"type:varchar(255)"` // 维修停用/暂停使用的原因
+	StateTime    time.Time `gorm:"type:datetime"`     // 最近一次变为待清扫或由客房部修改状态的时间
+	DailyRate    float32   `gorm:"type:float(7,2)"`   // 每日房费
+	Deposit      float32   `gorm:"type:float(10,2)"`  // 押金
 }
 
 // 房间账单记录表
@@ -4,6 +4,7 @@ type Scheduler struct {
 	ACID               int
 	BillID             int
 	RoomID             int
+	RoomTypeID         int // 房间类型ID，用于房间热模型
 	ACState            int //0-运行 1-在等待序列 2-关机回温 3-达到目标温度回温
 	Mode               string
 	Priority           int // 1: high, 2: medium, 3: low
@@ -11,8 +12,8 @@ type Scheduler struct {
 	CurrentTemp        int
 	TargetTemp         int
 	EnvironmentTemp    int
-	CurrentCost        int
-	TotalCost          int
+	CurrentCost        float32 // 本次开机的花费金额
+	TotalCost          float32 // 本次入住的空调总花费金额
 	RunningTime        int
 	CurrentRunningTime int
 	RoundRobinCount    int
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// 刷新令牌状态
+const (
+	RefreshTokenActive  = 0 // 可用
+	RefreshTokenRotated = 1 // 已轮换，再次使用视为令牌泄露
+	RefreshTokenRevoked = 2 // 已吊销（退出登录、检测到重复使用或管理员吊销）
+)
+
+// 刷新令牌表
+// 只保存令牌的SHA-256哈希；每次刷新轮换出新的令牌，同一次登录轮换出的令牌属于同一会话
+type RefreshToken struct {
+	ID        int       `gorm:"primaryKey;autoIncrement"`
+	UserID    int       `gorm:"type:int;index"`
+	SessionID string    `gorm:"type:varchar(64);index"`       // 登录会话ID，与访问令牌中的sid一致
+	TokenHash string    `gorm:"type:varchar(64);uniqueIndex"` // 刷新令牌的SHA-256哈希
+	AccessJTI string    `gorm:"type:varchar(64)"`             // 同时签发的访问令牌的jti，吊销会话时一并吊销
+	State     int       `gorm:"type:int;index"`               // 0: 可用 1: 已轮换 2: 已吊销
+	ExpiresAt time.Time `gorm:"type:datetime"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// 已吊销的访问令牌表
+// 认证中间件按jti检查访问令牌是否已吊销，令牌过期后记录可以清理
+type RevokedToken struct {
+	JTI       string    `gorm:"type:varchar(64);primaryKey"`
+	UserID    int       `gorm:"type:int;index"`
+	ExpiresAt time.Time `gorm:"type:datetime;index"` // 访问令牌的过期时间
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -5,5 +5,6 @@ type User struct {
 	ID       int    `gorm:"primary_key;auto_increment"`
 	Username string `gorm:"type:varchar(255);unique;not null"`
 	Password string `gorm:"type:varchar(255);not null"`
-	Identity string `gorm:"type:varchar(255);not null"` // customer, administrator
+	Identity string `gorm:"type:varchar(255);not null"` // 角色名称，见角色表（customer、administrator、front_desk等）
+	Disabled bool   // 已停用的账户不能登录和刷新令牌
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bupt-hotel/simulation"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runSimulate 执行 simulate 子命令：在虚拟时钟上回放场景并输出逐tick状态表
+// 用法: bupt-hotel simulate [-o result.csv|result.xlsx] [-policy name] [-log] scenario.yaml
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	output := fs.String("o", "", "输出文件路径，.xlsx 输出Excel，其余输出CSV；为空时输出CSV到标准输出")
+	policy := fs.String("policy", "", "覆盖场景文件中的调度策略")
+	verbose := fs.Bool("log", false, "输出调度器日志到标准错误")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("用法: bupt-hotel simulate [-o 输出文件] [-policy 策略] [-log] 场景文件.yaml")
+	}
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	scenario, err := simulation.LoadScenario(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *policy != "" {
+		scenario.Policy = *policy
+	}
+
+	rows, err := simulation.Run(scenario)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *output == "":
+		return simulation.WriteCSV(os.Stdout, rows)
+	case strings.EqualFold(filepath.Ext(*output), ".xlsx"):
+		return simulation.WriteExcel(*output, rows)
+	default:
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return simulation.WriteCSV(file, rows)
+	}
+}
@@ -0,0 +1,89 @@
+package simulation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// tableHeader 输出表头
+var tableHeader = []string{"Tick", "房间号", "空调ID", "空调状态", "所在队列", "模式", "风速", "优先级", "当前温度", "目标温度", "当前费用", "总费用"}
+
+// values 将状态行转换为表格单元格，温度按实际摄氏度输出
+func (r Row) values() []interface{} {
+	return []interface{}{
+		r.Tick,
+		r.RoomID,
+		r.ACID,
+		r.State,
+		r.Queue,
+		r.Mode,
+		r.Speed,
+		r.Priority,
+		float64(r.CurrentTemp) / 10.0,
+		float64(r.TargetTemp) / 10.0,
+		r.CurrentCost,
+		r.TotalCost,
+	}
+}
+
+// WriteCSV 以CSV格式输出仿真结果
+func WriteCSV(w io.Writer, rows []Row) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(tableHeader); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		record := make([]string, 0, len(tableHeader))
+		for _, value := range row.values() {
+			switch v := value.(type) {
+			case float64:
+				record = append(record, strconv.FormatFloat(v, 'f', -1, 64))
+			default:
+				record = append(record, fmt.Sprint(v))
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteExcel 以Excel格式保存仿真结果
+func WriteExcel(path string, rows []Row) error {
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	sheetName := "调度仿真"
+	f.SetSheetName("Sheet1", sheetName)
+
+	for i, title := range tableHeader {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheetName, cell, title)
+	}
+
+	for r, row := range rows {
+		for i, value := range row.values() {
+			cell, _ := excelize.CoordinatesToCellName(i+1, r+2)
+			f.SetCellValue(sheetName, cell, value)
+		}
+	}
+
+	// 设置列宽
+	f.SetColWidth(sheetName, "A", "C", 8)
+	f.SetColWidth(sheetName, "D", "D", 18)
+	f.SetColWidth(sheetName, "E", "L", 10)
+
+	return f.SaveAs(path)
+}
@@ -0,0 +1,187 @@
+package simulation
+
+import (
+	"time"
+
+	"bupt-hotel/handlers"
+	"bupt-hotel/models"
+)
+
+// 队列名称
+const (
+	QueueServing = "serving" // 服务队列
+	QueueBuffer  = "buffer"  // 缓冲队列（等待中）
+	QueueWarming = "warming" // 回温队列
+	QueueNone    = "-"       // 未进入调度器
+)
+
+// Row 某个tick结束时一个房间的空调状态
+type Row struct {
+	Tick        int
+	RoomID      int
+	ACID        int
+	State       string
+	Queue       string
+	Mode        string
+	Speed       string
+	Priority    int
+	CurrentTemp int // 当前温度*10
+	TargetTemp  int // 目标温度*10
+	CurrentCost float64
+	TotalCost   float64
+}
+
+// roomControl 记录房间最近一次的空调设置，对应ControlAirConditioner中的上一次操作记录
+type roomControl struct {
+	room       Room
+	mode       string
+	speed      string
+	targetTemp int
+}
+
+// Run 在虚拟时钟上运行场景，返回每个tick每个房间的状态
+// tick 0 只应用事件不推进调度器；之后每个tick先执行一次调度，再应用该tick的事件并记录状态
+func Run(sc *Scenario) ([]Row, error) {
+	policy, err := handlers.NewSchedulingPolicy(sc.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	thermal, err := handlers.NewThermalModel(sc.Thermal.Model, sc.Thermal.RoomTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未指定起始时间时从2025-01-01 00:00开始
+	start := sc.start
+	if start.IsZero() {
+		start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+	}
+
+	scheduler := handlers.NewACScheduler(handlers.SchedulerOptions{
+		Clock:              handlers.NewVirtualClock(start),
+		Config:             sc.Scheduler,
+		Policy:             policy,
+		Thermal:            thermal,
+		Tariff:             &sc.Tariff,
+		DisablePersistence: true,
+	})
+
+	controls := make(map[int]*roomControl, len(sc.Rooms))
+	for _, room := range sc.Rooms {
+		controls[room.RoomID] = &roomControl{
+			room:       room,
+			mode:       "heating", // 与ControlAirConditioner开机默认值一致
+			speed:      "medium",
+			targetTemp: 220,
+		}
+	}
+
+	rows := make([]Row, 0, (sc.Ticks+1)*len(sc.Rooms))
+	next := 0
+	for tick := 0; tick <= sc.Ticks; tick++ {
+		if tick > 0 {
+			scheduler.Step(1)
+		}
+
+		for next < len(sc.Events) && sc.Events[next].Tick == tick {
+			applyEvent(scheduler, controls[sc.Events[next].RoomID], sc.Events[next])
+			next++
+		}
+
+		rows = append(rows, collectRows(tick, scheduler.Snapshot(), sc.Rooms, controls)...)
+	}
+	return rows, nil
+}
+
+// applyEvent 将场景事件转换为调度器请求，行为与ControlAirConditioner保持一致
+func applyEvent(scheduler *handlers.ACScheduler, control *roomControl, event Event) {
+	if event.Mode != "" {
+		control.mode = event.Mode
+	}
+	if event.Speed != "" {
+		control.speed = event.Speed
+	}
+	if event.TargetTemp > 0 {
+		control.targetTemp = event.TargetTemp
+	}
+
+	switch event.Action {
+	case ActionOn:
+		scheduler.AddRequest(&models.Scheduler{
+			ACID:            control.room.ACID,
+			RoomID:          control.room.RoomID,
+			RoomTypeID:      control.room.RoomTypeID,
+			ACState:         0,
+			Mode:            control.mode,
+			Priority:        handlers.PriorityForSpeed(control.speed),
+			CurrentSpeed:    control.speed,
+			CurrentTemp:     control.room.InitialTemp,
+			TargetTemp:      control.targetTemp,
+			EnvironmentTemp: control.room.EnvironmentTemp,
+		})
+	case ActionOff:
+		scheduler.RemoveRequest(control.room.ACID)
+	case ActionSet:
+		scheduler.UpdateACInBuffer(control.room.ACID, control.targetTemp, control.speed, handlers.PriorityForSpeed(control.speed))
+	}
+}
+
+// collectRows 根据调度器快照生成每个房间的状态行
+func collectRows(tick int, snapshot handlers.SchedulerSnapshot, rooms []Room, controls map[int]*roomControl) []Row {
+	rows := make([]Row, 0, len(rooms))
+	for _, room := range rooms {
+		ac, queue := findAC(snapshot, room.ACID)
+		if ac == nil {
+			control := controls[room.RoomID]
+			rows = append(rows, Row{
+				Tick:        tick,
+				RoomID:      room.RoomID,
+				ACID:        room.ACID,
+				State:       "关机",
+				Queue:       QueueNone,
+				Mode:        control.mode,
+				Speed:       control.speed,
+				CurrentTemp: room.InitialTemp,
+				TargetTemp:  control.targetTemp,
+			})
+			continue
+		}
+
+		rows = append(rows, Row{
+			Tick:        tick,
+			RoomID:      room.RoomID,
+			ACID:        ac.ACID,
+			State:       handlers.ACStatusText(ac.ACState),
+			Queue:       queue,
+			Mode:        ac.Mode,
+			Speed:       ac.CurrentSpeed,
+			Priority:    ac.Priority,
+			CurrentTemp: ac.CurrentTemp,
+			TargetTemp:  ac.TargetTemp,
+			CurrentCost: float64(ac.CurrentCost),
+			TotalCost:   float64(ac.TotalCost),
+		})
+	}
+	return rows
+}
+
+// findAC 在快照中查找空调，服务队列优先于缓冲队列
+func findAC(snapshot handlers.SchedulerSnapshot, acID int) (*models.Scheduler, string) {
+	queues := []struct {
+		name  string
+		items []models.Scheduler
+	}{
+		{QueueServing, snapshot.ServingQueue},
+		{QueueBuffer, snapshot.BufferQueue},
+		{QueueWarming, snapshot.WarmingQueue},
+	}
+	for _, queue := range queues {
+		for i := range queue.items {
+			if queue.items[i].ACID == acID {
+				return &queue.items[i], queue.name
+			}
+		}
+	}
+	return nil, QueueNone
+}
@@ -0,0 +1,150 @@
+package simulation
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"bupt-hotel/handlers"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 场景事件动作
+const (
+	ActionOn  = "on"  // 开机
+	ActionOff = "off" // 关机
+	ActionSet = "set" // 调温/调风
+)
+
+// Scenario 空调调度测试场景
+type Scenario struct {
+	Name      string                   `yaml:"name"`
+	Policy    string                   `yaml:"policy"`     // 调度策略，为空时使用默认策略
+	Scheduler handlers.SchedulerConfig `yaml:"scheduler"`  // 调度器参数，未设置的字段使用默认值
+	Thermal   Thermal                  `yaml:"thermal"`    // 房间热模型
+	Tariff    handlers.Tariff          `yaml:"tariff"`     // 计费规则，未设置的风速费率使用默认值
+	StartTime string                   `yaml:"start_time"` // 虚拟时钟起始时间，格式 2006-01-02 15:04，用于分时计费
+	Ticks     int                      `yaml:"ticks"`      // 仿真总tick数
+	Rooms     []Room                   `yaml:"rooms"`
+	Events    []Event                  `yaml:"events"`
+
+	start time.Time
+}
+
+// ScenarioTimeLayout 场景起始时间格式
+const ScenarioTimeLayout = "2006-01-02 15:04"
+
+// Thermal 场景使用的房间热模型及各房间类型的热参数
+type Thermal struct {
+	Model     string                             `yaml:"model"` // linear/newtonian，为空时使用线性模型
+	RoomTypes map[int]handlers.RoomThermalParams `yaml:"room_types"`
+}
+
+// Room 场景中的房间及其空调初始状态
+type Room struct {
+	RoomID          int `yaml:"room_id"`
+	ACID            int `yaml:"ac_id"`            // 空调ID，为0时与房间号相同
+	RoomTypeID      int `yaml:"room_type_id"`     // 房间类型ID，用于牛顿冷却模型选择热参数
+	InitialTemp     int `yaml:"initial_temp"`     // 初始温度*10，为0时等于环境温度
+	EnvironmentTemp int `yaml:"environment_temp"` // 环境温度*10
+}
+
+// Event 场景中某个tick发生的空调操作
+type Event struct {
+	Tick       int    `yaml:"tick"`
+	RoomID     int    `yaml:"room_id"`
+	Action     string `yaml:"action"`      // on/off/set
+	Mode       string `yaml:"mode"`        // cooling/heating
+	Speed      string `yaml:"speed"`       // high/medium/low
+	TargetTemp int    `yaml:"target_temp"` // 目标温度*10
+}
+
+// LoadScenario 从YAML文件加载场景
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取场景文件失败: %w", err)
+	}
+
+	scenario := Scenario{Scheduler: handlers.DefaultSchedulerConfig(), Tariff: handlers.DefaultTariff()}
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("解析场景文件失败: %w", err)
+	}
+
+	if err := scenario.normalize(); err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+// normalize 校验场景并补全默认值，事件按tick稳定排序
+func (sc *Scenario) normalize() error {
+	if err := sc.Scheduler.Validate(); err != nil {
+		return err
+	}
+	if err := sc.Tariff.Validate(); err != nil {
+		return err
+	}
+
+	if sc.StartTime != "" {
+		start, err := time.ParseInLocation(ScenarioTimeLayout, sc.StartTime, time.Local)
+		if err != nil {
+			return fmt.Errorf("场景起始时间格式错误（应为 %s）: %s", ScenarioTimeLayout, sc.StartTime)
+		}
+		sc.start = start
+	}
+	if len(sc.Rooms) == 0 {
+		return fmt.Errorf("场景中没有房间")
+	}
+
+	rooms := make(map[int]bool, len(sc.Rooms))
+	for i := range sc.Rooms {
+		room := &sc.Rooms[i]
+		if rooms[room.RoomID] {
+			return fmt.Errorf("房间 %d 重复定义", room.RoomID)
+		}
+		rooms[room.RoomID] = true
+
+		if room.ACID == 0 {
+			room.ACID = room.RoomID
+		}
+		if room.EnvironmentTemp == 0 {
+			room.EnvironmentTemp = 250
+		}
+		if room.InitialTemp == 0 {
+			room.InitialTemp = room.EnvironmentTemp
+		}
+	}
+
+	maxTick := 0
+	for _, event := range sc.Events {
+		if !rooms[event.RoomID] {
+			return fmt.Errorf("tick %d 的事件引用了未定义的房间 %d", event.Tick, event.RoomID)
+		}
+		if event.Tick < 0 {
+			return fmt.Errorf("房间 %d 的事件tick不能为负数", event.RoomID)
+		}
+		switch event.Action {
+		case ActionOn, ActionOff, ActionSet:
+		default:
+			return fmt.Errorf("tick %d 房间 %d 的事件动作无效: %q", event.Tick, event.RoomID, event.Action)
+		}
+		if event.Tick > maxTick {
+			maxTick = event.Tick
+		}
+	}
+
+	// 未指定总tick数时，运行到最后一个事件之后；指定的总tick数不能截断事件
+	if sc.Ticks <= 0 {
+		sc.Ticks = maxTick + 1
+	} else if maxTick > sc.Ticks {
+		return fmt.Errorf("tick %d 的事件超出了场景总tick数 %d", maxTick, sc.Ticks)
+	}
+
+	sort.SliceStable(sc.Events, func(i, j int) bool {
+		return sc.Events[i].Tick < sc.Events[j].Tick
+	})
+	return nil
+}
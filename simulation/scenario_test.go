@@ -0,0 +1,54 @@
+package simulation
+
+import (
+	"strings"
+	"testing"
+
+	"bupt-hotel/handlers"
+)
+
+func newTestScenario(ticks int, events ...Event) *Scenario {
+	return &Scenario{
+		Scheduler: handlers.DefaultSchedulerConfig(),
+		Tariff:    handlers.DefaultTariff(),
+		Ticks:     ticks,
+		Rooms:     []Room{{RoomID: 101, EnvironmentTemp: 250}},
+		Events:    events,
+	}
+}
+
+func TestNormalizeRejectsEventsAfterTicks(t *testing.T) {
+	sc := newTestScenario(30, Event{Tick: 0, RoomID: 101, Action: ActionOn}, Event{Tick: 300, RoomID: 101, Action: ActionOff})
+
+	err := sc.normalize()
+	if err == nil {
+		t.Fatal("事件tick超出总tick数时应返回错误")
+	}
+	if !strings.Contains(err.Error(), "300") {
+		t.Fatalf("错误信息应包含超出的tick: %v", err)
+	}
+}
+
+func TestNormalizeTicks(t *testing.T) {
+	tests := []struct {
+		name  string
+		ticks int
+		want  int
+	}{
+		{name: "未指定时运行到最后一个事件之后", ticks: 0, want: 21},
+		{name: "等于最后一个事件的tick", ticks: 20, want: 20},
+		{name: "大于最后一个事件的tick", ticks: 50, want: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := newTestScenario(tt.ticks, Event{Tick: 0, RoomID: 101, Action: ActionOn}, Event{Tick: 20, RoomID: 101, Action: ActionOff})
+			if err := sc.normalize(); err != nil {
+				t.Fatalf("normalize 返回错误: %v", err)
+			}
+			if sc.Ticks != tt.want {
+				t.Fatalf("总tick数为 %d，期望 %d", sc.Ticks, tt.want)
+			}
+		})
+	}
+}